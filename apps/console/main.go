@@ -96,18 +96,39 @@ func (c *Console) SetBounds(rect image.Rectangle) {
 	}
 }
 
+// cellRect returns the area the glyph of the cell at x, y is drawn in,
+// from its baseline down to the descent.
+func (c *Console) cellRect(x, y int) image.Rectangle {
+	descent := c.cellSize.Y / 4
+	return image.Rect(
+		x*c.cellSize.X+c.cellSize.X, y*c.cellSize.Y+descent,
+		x*c.cellSize.X+2*c.cellSize.X, y*c.cellSize.Y+c.cellSize.Y+descent,
+	)
+}
+
+func (c *Console) damageCell(x, y int) {
+	c.AddDamage(c.cellRect(x, y))
+}
+
+// Draw repaints only the cells that changed since the last draw.
 func (c *Console) Draw(cv canvas.Canvas) {
-	graphics.FillRect(cv, c.Bounds(), 0, c.BackgroundColor)
-	for y, row := range c.buffer {
-		for x, ch := range row {
-			pos := image.Point{
-				X: x*c.cellSize.X + c.cellSize.X,
-				Y: y*c.cellSize.Y + c.cellSize.Y,
-			}
-			if x == c.cursor.X && y == c.cursor.Y {
-				graphics.Text(cv, pos, string(ch), c.fontSize, argb.NewColor(0, 0, 0, 255))
-			} else {
-				graphics.Text(cv, pos, string(ch), c.fontSize, argb.NewColor(255, 255, 255, 255))
+	for _, r := range c.Damage() {
+		clip := canvas.Clip(cv, r)
+		graphics.FillRect(clip, r, 0, c.BackgroundColor)
+		for y, row := range c.buffer {
+			for x, ch := range row {
+				if !c.cellRect(x, y).Overlaps(r) {
+					continue
+				}
+				pos := image.Point{
+					X: x*c.cellSize.X + c.cellSize.X,
+					Y: y*c.cellSize.Y + c.cellSize.Y,
+				}
+				if x == c.cursor.X && y == c.cursor.Y {
+					graphics.Text(clip, pos, string(ch), c.fontSize, argb.NewColor(0, 0, 0, 255))
+				} else {
+					graphics.Text(clip, pos, string(ch), c.fontSize, argb.NewColor(255, 255, 255, 255))
+				}
 			}
 		}
 	}
@@ -123,6 +144,7 @@ func (c *Console) Update(ev event.Event) bool {
 		}
 
 		log.Println("Got pty event:", string(ev))
+		c.damageCell(c.cursor.X, c.cursor.Y)
 		for i := 0; i < len(ev); i++ {
 			ch := ev[i]
 
@@ -161,10 +183,12 @@ func (c *Console) Update(ev event.Event) bool {
 					c.cursor.X = c.cols - 1
 					c.buffer[c.cursor.Y][c.cursor.X] = ' '
 				}
+				c.damageCell(c.cursor.X, c.cursor.Y)
 			default:
 				if ch >= 32 && ch < 127 {
 					if c.cursor.Y < len(c.buffer) && c.cursor.X < len(c.buffer[c.cursor.Y]) {
 						c.buffer[c.cursor.Y][c.cursor.X] = ch
+						c.damageCell(c.cursor.X, c.cursor.Y)
 					}
 					c.cursor.X++
 					if c.cursor.X >= c.cols {
@@ -178,7 +202,7 @@ func (c *Console) Update(ev event.Event) bool {
 				}
 			}
 		}
-		c.SetDirty(true)
+		c.damageCell(c.cursor.X, c.cursor.Y)
 
 	case key.Event:
 		if ev.State == key.Pressed {
@@ -204,6 +228,7 @@ func (c *Console) Update(ev event.Event) bool {
 }
 
 func (c *Console) scrollUp() {
+	c.SetDirty(true)
 	copy(c.buffer[0:], c.buffer[1:])
 	c.buffer[c.rows-1] = make([]byte, c.cols)
 	for x := range c.buffer[c.rows-1] {
@@ -241,6 +266,7 @@ func (c *Console) processEscape(buf []byte) {
 }
 
 func (c *Console) clearScreen() {
+	c.SetDirty(true)
 	for y := range c.buffer {
 		for x := range c.buffer[y] {
 			c.buffer[y][x] = ' '
@@ -250,6 +276,7 @@ func (c *Console) clearScreen() {
 }
 
 func (c *Console) moveCursor(row, col int) {
+	c.damageCell(c.cursor.X, c.cursor.Y)
 	if row >= 0 && row < c.rows {
 		c.cursor.Y = row
 	}
@@ -0,0 +1,95 @@
+/*
+ * Copyright (c) 2025 Manjeet Singh <itsmanjeet1998@gmail.com>.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 3.
+ *
+ * This program is distributed in the hope that it will be useful, but
+ * WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+ * General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+package main
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"chillos/pkg/boot"
+	"chillos/pkg/kernel/rtc"
+)
+
+const (
+	// only the modification time matters, it is touched at shutdown
+	CLOCK_PATH = boot.DataPath + "/system/clock"
+)
+
+// restoreClock moves the system clock forward to the newest of the RTC
+// and the timestamp saved at the last shutdown, so that it never goes
+// backwards on machines without a working RTC.
+func restoreClock() {
+	now := time.Now()
+	newest := now
+
+	if clock, err := rtc.Open(rtc.DevicePath); err == nil {
+		if t, err := clock.Read(); err == nil {
+			if t.After(newest) {
+				newest = t
+			}
+		} else {
+			log.Printf("failed to read rtc: %v", err)
+		}
+		clock.Close()
+	}
+
+	if info, err := os.Stat(CLOCK_PATH); err == nil && info.ModTime().After(newest) {
+		newest = info.ModTime()
+	}
+
+	if newest.Sub(now) < time.Second {
+		return
+	}
+
+	tv := syscall.NsecToTimeval(newest.UnixNano())
+	if err := syscall.Settimeofday(&tv); err != nil {
+		log.Printf("failed to set clock: %v", err)
+		return
+	}
+	log.Printf("clock moved forward to %s", newest.UTC().Format(time.RFC3339))
+}
+
+// saveClock writes the system time back to the RTC and the saved
+// timestamp.
+func saveClock() {
+	now := time.Now()
+
+	if clock, err := rtc.Open(rtc.DevicePath); err == nil {
+		if err := clock.Set(now); err != nil {
+			log.Printf("failed to set rtc: %v", err)
+		}
+		clock.Close()
+	}
+
+	if err := os.MkdirAll(filepath.Dir(CLOCK_PATH), 0755); err != nil {
+		log.Printf("failed to save clock: %v", err)
+		return
+	}
+	file, err := os.OpenFile(CLOCK_PATH, os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		log.Printf("failed to save clock: %v", err)
+		return
+	}
+	file.Close()
+
+	if err := os.Chtimes(CLOCK_PATH, now, now); err != nil {
+		log.Printf("failed to save clock: %v", err)
+	}
+}
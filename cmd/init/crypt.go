@@ -0,0 +1,129 @@
+/*
+ * Copyright (c) 2025 Manjeet Singh <itsmanjeet1998@gmail.com>.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 3.
+ *
+ * This program is distributed in the hope that it will be useful, but
+ * WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+ * General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"chillos/pkg/crypt"
+	"chillos/pkg/kernel/dm"
+	"chillos/pkg/kernel/ioctl"
+)
+
+const (
+	CRYPT_DEVICE_NAME = "data"
+	CRYPT_MAX_TRIES   = 3
+)
+
+// unlockDevice asks for the passphrase of an encrypted device on the
+// console and maps it through dm-crypt. Plain devices are returned as is.
+func unlockDevice(device string) (string, error) {
+	file, err := os.Open(device)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	header, err := crypt.ReadHeader(file)
+	if err != nil {
+		if err == crypt.ErrNotEncrypted {
+			return device, nil
+		}
+		return "", err
+	}
+
+	size, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return "", err
+	}
+	offset := uint64(header.PayloadOffset)
+	sectors := uint64(size / crypt.SectorSize)
+	if sectors <= offset {
+		return "", fmt.Errorf("%s is too small for its payload", device)
+	}
+
+	console, err := os.OpenFile("/dev/console", os.O_RDWR, 0)
+	if err != nil {
+		return "", err
+	}
+	defer console.Close()
+
+	var key []byte
+	for i := 0; i < CRYPT_MAX_TRIES && key == nil; i++ {
+		passphrase, err := readPassphrase(console, fmt.Sprintf("Passphrase for %s: ", device))
+		if err != nil {
+			return "", err
+		}
+
+		if key, err = header.Unlock(passphrase); err != nil {
+			fmt.Fprintln(console, "Wrong passphrase")
+		}
+	}
+	if key == nil {
+		return "", crypt.ErrWrongPassword
+	}
+	defer clear(key)
+
+	control, err := dm.Open()
+	if err != nil {
+		return "", err
+	}
+	defer control.Close()
+
+	target := dm.Crypt{
+		Cipher:  header.CipherSpec(),
+		Key:     key,
+		Device:  device,
+		Offset:  offset,
+		Sectors: sectors - offset,
+	}
+	dev, err := control.Create(CRYPT_DEVICE_NAME, "", 0, []dm.Target{target.Target()})
+	if err != nil {
+		return "", err
+	}
+	return dev.Path(), nil
+}
+
+func readPassphrase(console *os.File, prompt string) (string, error) {
+	var state syscall.Termios
+	if err := ioctl.Call(console.Fd(), syscall.TCGETS, unsafe.Pointer(&state)); err == nil {
+		noecho := state
+		noecho.Lflag &^= syscall.ECHO
+		noecho.Lflag |= syscall.ICANON
+		if err := ioctl.Call(console.Fd(), syscall.TCSETS, unsafe.Pointer(&noecho)); err == nil {
+			defer ioctl.Call(console.Fd(), syscall.TCSETS, unsafe.Pointer(&state))
+		}
+	}
+
+	if _, err := console.WriteString(prompt); err != nil {
+		return "", err
+	}
+	defer console.WriteString("\n")
+
+	line, err := bufio.NewReader(console).ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
@@ -25,13 +25,28 @@ import (
 	"strings"
 	"syscall"
 
+	"chillos/pkg/boot"
 	"chillos/pkg/ensure"
+	"chillos/pkg/kernel/watchdog"
 )
 
 var (
 	errors []string
 	rootfs string
 
+	dataDevice     string
+	dataFilesystem string
+	slotDevices    = map[boot.Slot]*string{
+		boot.SlotA: new(string),
+		boot.SlotB: new(string),
+	}
+
+	rootHash       string
+	slotRootHashes = map[boot.Slot]*string{
+		boot.SlotA: new(string),
+		boot.SlotB: new(string),
+	}
+
 	kernelFlags = flag.NewFlagSet("kernel", flag.ContinueOnError)
 )
 
@@ -77,6 +92,28 @@ func ensureRealRootfs() {
 	safeCall("parse(/proc/cmdline)", parseKernelFlags())
 	ensureStage("parsing kernel args")
 
+	if dataDevice != "" {
+		device, err := unlockDevice(dataDevice)
+		safeCall("unlock("+dataDevice+")", err)
+		ensureStage("unlock data partition")
+
+		safeCall("mkdir(data)", os.MkdirAll(boot.DataPath, 0755))
+		safeCall("mount(data)", syscall.Mount(device, boot.DataPath, dataFilesystem, syscall.MS_NOSUID|syscall.MS_NODEV, ""))
+		ensureStage("mount data partition")
+	}
+
+	slot, err := selectSlot()
+	if err != nil {
+		log.Printf("failed to select system slot: %v", err)
+	} else if slot != "" {
+		log.Printf("booting system slot %s from %s", slot, rootfs)
+	}
+	if slot != "" {
+		if err := boot.SaveBooted(slot); err != nil {
+			log.Printf("failed to record booted slot: %v", err)
+		}
+	}
+
 	if _, err := os.Stat(rootfs); err != nil {
 		blocks, err := os.ReadDir("/sys/block")
 		if err != nil {
@@ -87,6 +124,14 @@ func ensureRealRootfs() {
 		}
 		log.Fatal("no root device present ", rootfs)
 	}
+
+	if rootHash != "" {
+		verified, err := setupVerity(rootfs, rootHash)
+		safeCall("verity("+rootfs+")", err)
+		ensureStage("verify system image")
+		rootfs = verified
+	}
+
 	for _, dir := range []string{"/cache/temp/overlay", "/cache/temp/overlay/ro", "/cache/temp/overlay/rw", "/cache/temp/overlay/work", "/rootfs"} {
 		safeCall("mkdir("+dir+")", syscall.Mkdir(dir, 0755))
 	}
@@ -94,7 +139,11 @@ func ensureRealRootfs() {
 	safeCall("mount(overlay)", syscall.Mount("overlay", "/rootfs", "overlay", 0, "lowerdir=/cache/temp/overlay/ro,upperdir=/cache/temp/overlay/rw,workdir=/cache/temp/overlay/work"))
 	ensureStage("prepare real rootfs")
 
-	for _, fs := range []string{"proc", "sys", "dev", "cache/temp"} {
+	pseudoFilesystems := []string{"proc", "sys", "dev", "cache/temp"}
+	if dataDevice != "" {
+		pseudoFilesystems = append(pseudoFilesystems, "data")
+	}
+	for _, fs := range pseudoFilesystems {
 		safeCall("mkdir("+fs+")", os.MkdirAll("/rootfs/"+fs, 0755))
 		safeCall("mount("+fs+")", syscall.Mount("/"+fs, "/rootfs/"+fs, "", syscall.MS_MOVE, ""))
 	}
@@ -125,19 +174,34 @@ func ensureStage(stage string) {
 	}
 }
 
-func parseKernelFlags() error {
+func init() {
+	kernelFlags.StringVar(&rootfs, "rootfs", "", "Specify rootfs")
+	kernelFlags.StringVar(&dataDevice, "data", "", "Specify data partition")
+	kernelFlags.StringVar(&dataFilesystem, "data-fs", "ext4", "Specify data partition filesystem")
+	kernelFlags.StringVar(slotDevices[boot.SlotA], "slot-a", "", "Specify system image slot A")
+	kernelFlags.StringVar(slotDevices[boot.SlotB], "slot-b", "", "Specify system image slot B")
+	kernelFlags.StringVar(&rootHash, "roothash", "", "Specify dm-verity root hash of rootfs")
+	kernelFlags.StringVar(slotRootHashes[boot.SlotA], "roothash-a", "", "Specify dm-verity root hash of slot A")
+	kernelFlags.StringVar(slotRootHashes[boot.SlotB], "roothash-b", "", "Specify dm-verity root hash of slot B")
+	kernelFlags.StringVar(&watchdogDevice, "watchdog", watchdog.DevicePath, "Specify watchdog device, empty to disable")
+	kernelFlags.IntVar(&watchdogTimeout, "watchdog-timeout", DEFAULT_WATCHDOG_TIMEOUT, "Specify watchdog timeout in seconds, 0 to disable")
+}
+
+func readKernelFlags() error {
 	data, err := os.ReadFile("/proc/cmdline")
 	if err != nil {
 		return fmt.Errorf("failed to read kernel cmdline flags %v", err)
 	}
+	return kernelFlags.Parse(strings.Fields(string(data)))
+}
 
-	kernelFlags.StringVar(&rootfs, "rootfs", "", "Specify rootfs")
-	if err := kernelFlags.Parse(strings.Fields(string(data))); err != nil {
+func parseKernelFlags() error {
+	if err := readKernelFlags(); err != nil {
 		return err
 	}
 
-	if rootfs == "" {
-		return fmt.Errorf("no -rootfs specified")
+	if rootfs == "" && *slotDevices[boot.SlotA] == "" {
+		return fmt.Errorf("no -rootfs or -slot-a specified")
 	}
 
 	return nil
@@ -26,8 +26,14 @@ import (
 	"os/exec"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"chillos/pkg/ensure"
+	"chillos/pkg/kernel/kexec"
+)
+
+const (
+	SERVICE_MANAGER_TIMEOUT = 10 * time.Second
 )
 
 func main() {
@@ -52,6 +58,12 @@ func main() {
 	} {
 		os.Setenv(k, v)
 	}
+	if err := readKernelFlags(); err != nil {
+		log.Printf("failed to parse kernel cmdline: %v", err)
+	}
+	restoreClock()
+	loadRandomSeed()
+
 	ctxt, cancel := context.WithCancel(context.Background())
 
 	serviceManager, err := startServiceManager(ctxt)
@@ -77,9 +89,15 @@ func main() {
 		syscall.SIGCHLD,
 		syscall.SIGINT)
 
+	supervisor := startWatchdog()
+	petWatchdog := supervisor.ticker()
+
 	var rebootCommand int
 	for rebootCommand == 0 {
 		select {
+		case <-petWatchdog:
+			supervisor.tick()
+
 		case sig := <-signalChannel:
 			switch sig {
 			case syscall.SIGUSR1:
@@ -99,6 +117,8 @@ func main() {
 		}
 	}
 
+	supervisor.arm()
+
 	if serviceManager != nil {
 		_ = serviceManager.Signal(syscall.SIGINT)
 	}
@@ -106,13 +126,23 @@ func main() {
 	_ = syscall.Reboot(syscall.LINUX_REBOOT_CMD_CAD_ON)
 
 	waitForChildProcesses(syscall.WNOHANG)
-	<-ctxt.Done()
+	select {
+	case <-ctxt.Done():
+	case <-time.After(SERVICE_MANAGER_TIMEOUT):
+		log.Println("service manager did not finish in time")
+	}
+
+	finalStage()
 
-	syscall.Sync()
 	if rebootCommand == 0 {
 		rebootCommand = syscall.LINUX_REBOOT_CMD_POWER_OFF
 	}
 
+	if rebootCommand == syscall.LINUX_REBOOT_CMD_KEXEC && !kexec.Loaded() {
+		log.Println("no kernel staged for kexec, restarting")
+		rebootCommand = syscall.LINUX_REBOOT_CMD_RESTART
+	}
+
 	if err := syscall.Reboot(rebootCommand); err != nil {
 		log.Fatal(err)
 	}
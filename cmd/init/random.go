@@ -0,0 +1,51 @@
+/*
+ * Copyright (c) 2025 Manjeet Singh <itsmanjeet1998@gmail.com>.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 3.
+ *
+ * This program is distributed in the hope that it will be useful, but
+ * WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+ * General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+package main
+
+import (
+	"log"
+	"os"
+
+	"chillos/pkg/boot"
+	"chillos/pkg/kernel/random"
+)
+
+const (
+	RANDOM_SEED_PATH = boot.DataPath + "/system/random-seed"
+)
+
+// loadRandomSeed credits the seed saved at the last shutdown before any
+// service starts, TLS and shm keys need randomness early.
+func loadRandomSeed() {
+	if err := random.LoadSeed(RANDOM_SEED_PATH); err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("failed to load random seed: %v", err)
+			return
+		}
+
+		// first boot, make sure the next one has a seed
+		if err := random.SaveSeed(RANDOM_SEED_PATH); err != nil {
+			log.Printf("failed to save random seed: %v", err)
+		}
+	}
+}
+
+func saveRandomSeed() {
+	if err := random.SaveSeed(RANDOM_SEED_PATH); err != nil {
+		log.Printf("failed to save random seed: %v", err)
+	}
+}
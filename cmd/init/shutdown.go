@@ -0,0 +1,149 @@
+/*
+ * Copyright (c) 2025 Manjeet Singh <itsmanjeet1998@gmail.com>.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 3.
+ *
+ * This program is distributed in the hope that it will be useful, but
+ * WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+ * General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"log"
+	"slices"
+	"syscall"
+	"time"
+
+	"chillos/pkg/kernel/dm"
+	"chillos/pkg/kernel/loop"
+	"chillos/pkg/kernel/mount"
+)
+
+const (
+	TERMINATE_TIMEOUT = 5 * time.Second
+	KILL_TIMEOUT      = 2 * time.Second
+)
+
+var (
+	// kept mounted until the very end, nothing on them needs syncing
+	apiFilesystems = []string{"/", "/proc", "/sys", "/dev"}
+)
+
+// finalStage brings the system to a state where it is safe to call
+// reboot(2): no processes left, filesystems unmounted or read-only and
+// block devices detached.
+func finalStage() {
+	log.Println("sending SIGTERM to all processes")
+	killAllProcesses(syscall.SIGTERM, TERMINATE_TIMEOUT)
+
+	log.Println("sending SIGKILL to all processes")
+	killAllProcesses(syscall.SIGKILL, KILL_TIMEOUT)
+
+	saveRandomSeed()
+	saveClock()
+
+	syscall.Sync()
+	unmountFilesystems()
+	detachDevices()
+	syscall.Sync()
+}
+
+// killAllProcesses signals everything but init and reaps until no
+// children are left or timeout expires.
+func killAllProcesses(sig syscall.Signal, timeout time.Duration) {
+	if err := syscall.Kill(-1, sig); err != nil {
+		if err == syscall.ESRCH {
+			return
+		}
+		log.Printf("failed to send %v: %v", sig, err)
+	}
+
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		pid, err := syscall.Wait4(-1, nil, syscall.WNOHANG, nil)
+		if err == syscall.ECHILD {
+			return
+		}
+		if pid <= 0 {
+			time.Sleep(50 * time.Millisecond)
+		}
+	}
+}
+
+func unmountFilesystems() {
+	mounts, err := mount.ReadMountInfo()
+	if err != nil {
+		log.Printf("failed to read mounts: %v", err)
+		return
+	}
+
+	for _, m := range slices.Backward(mounts) {
+		if slices.Contains(apiFilesystems, m.MountPoint) {
+			continue
+		}
+
+		// EINVAL and ENOENT mean it went away with its parent already
+		err := syscall.Unmount(m.MountPoint, 0)
+		if err == nil || err == syscall.EINVAL || err == syscall.ENOENT {
+			continue
+		}
+		log.Printf("failed to unmount %s: %v, detaching lazily", m.MountPoint, err)
+
+		_ = syscall.Mount("", m.MountPoint, "", syscall.MS_REMOUNT|syscall.MS_RDONLY, "")
+		if err := syscall.Unmount(m.MountPoint, syscall.MNT_DETACH); err != nil {
+			log.Printf("failed to detach %s: %v", m.MountPoint, err)
+		}
+	}
+
+	if err := syscall.Mount("", "/", "", syscall.MS_REMOUNT|syscall.MS_RDONLY, ""); err != nil {
+		log.Printf("failed to remount / read-only: %v", err)
+	}
+}
+
+func detachDevices() {
+	if devices, err := loop.Attached(); err == nil {
+		for _, device := range devices {
+			if err := loop.Detach(device); err != nil {
+				log.Printf("failed to detach %s: %v", device, err)
+			}
+		}
+	}
+
+	control, err := dm.Open()
+	if err != nil {
+		return
+	}
+	defer control.Close()
+
+	devices, err := control.List()
+	if err != nil {
+		return
+	}
+
+	// mapped devices can stack, retry while we make progress
+	for len(devices) > 0 {
+		var remaining []dm.Device
+		for _, device := range devices {
+			if err := control.Remove(device.Name); err != nil {
+				remaining = append(remaining, device)
+			}
+		}
+
+		if len(remaining) == len(devices) {
+			for _, device := range remaining {
+				log.Printf("failed to remove mapped device %s", device.Name)
+			}
+			break
+		}
+		devices = remaining
+	}
+}
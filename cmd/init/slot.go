@@ -0,0 +1,91 @@
+/*
+ * Copyright (c) 2025 Manjeet Singh <itsmanjeet1998@gmail.com>.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 3.
+ *
+ * This program is distributed in the hope that it will be useful, but
+ * WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+ * General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"chillos/pkg/boot"
+)
+
+// selectSlot points rootfs to the system image slot that should be
+// booted. Without -slot-a the plain -rootfs device is used as is.
+func selectSlot() (boot.Slot, error) {
+	if *slotDevices[boot.SlotA] == "" {
+		return "", nil
+	}
+
+	state, err := boot.LoadState(boot.StatePath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("resetting boot state: %v", err)
+		}
+		state = boot.NewState()
+	}
+
+	for slot, device := range slotDevices {
+		if *device != "" {
+			state.Devices[slot] = *device
+		}
+	}
+
+	slot, fallback := state.Pick()
+	if fallback {
+		log.Printf("slot %s failed to boot %d times, falling back to slot %s", state.Previous, state.MaxTries, slot)
+	}
+
+	// a slot without a trusted root hash must not get around verified boot
+	if slotRootHash(slot) == "" && slotRootHash(slot.Other()) != "" {
+		log.Printf("refusing to boot unverified slot %s, falling back to slot %s", slot, slot.Other())
+		state.Fallback()
+		slot = state.Active
+	}
+	rootHash = slotRootHash(slot)
+
+	device, ok := state.Devices[slot]
+	if !ok {
+		rootfs = *slotDevices[boot.SlotA]
+		return boot.SlotA, fmt.Errorf("no device for slot %s", slot)
+	}
+	rootfs = device
+
+	if dataDevice == "" {
+		return slot, nil
+	}
+
+	if err := state.Save(boot.StatePath); err != nil {
+		return slot, fmt.Errorf("failed to save boot state: %v", err)
+	}
+	return slot, nil
+}
+
+// slotRootHash returns the root hash slot is verified against. Only the
+// kernel command line is trusted, the boot state lives on the writable
+// data partition.
+func slotRootHash(slot boot.Slot) string {
+	if hash := *slotRootHashes[slot]; hash != "" {
+		return hash
+	}
+	if slot == boot.SlotA {
+		// -roothash describes what -slot-a or -rootfs points to
+		return rootHash
+	}
+	return ""
+}
@@ -0,0 +1,85 @@
+/*
+ * Copyright (c) 2025 Manjeet Singh <itsmanjeet1998@gmail.com>.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 3.
+ *
+ * This program is distributed in the hope that it will be useful, but
+ * WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+ * General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"os"
+
+	"chillos/pkg/kernel/dm"
+)
+
+const (
+	SQUASHFS_MAGIC = 0x73717368
+
+	VERITY_DEVICE_NAME = "system"
+)
+
+// setupVerity maps device through a dm-verity target checked against
+// rootHash and returns the path of the verified device. The hash tree is
+// expected right after the squashfs image, aligned to a verity block.
+func setupVerity(device string, rootHash string) (string, error) {
+	hash, err := hex.DecodeString(rootHash)
+	if err != nil {
+		return "", fmt.Errorf("invalid root hash %q: %v", rootHash, err)
+	}
+
+	file, err := os.Open(device)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	offset, err := squashfsSize(file)
+	if err != nil {
+		return "", err
+	}
+	offset = (offset + dm.VerityBlockSize - 1) &^ (dm.VerityBlockSize - 1)
+
+	sb, err := dm.ReadVeritySuperblock(file, offset)
+	if err != nil {
+		return "", err
+	}
+
+	control, err := dm.Open()
+	if err != nil {
+		return "", err
+	}
+	defer control.Close()
+
+	verity := dm.VerityFromSuperblock(sb, device, device, offset, hash)
+	dev, err := control.Create(VERITY_DEVICE_NAME, "", dm.ReadOnlyFlag, []dm.Target{verity.Target()})
+	if err != nil {
+		return "", err
+	}
+	return dev.Path(), nil
+}
+
+func squashfsSize(file *os.File) (int64, error) {
+	header := make([]byte, 48)
+	if _, err := file.ReadAt(header, 0); err != nil {
+		return 0, fmt.Errorf("failed to read squashfs superblock: %v", err)
+	}
+
+	if binary.LittleEndian.Uint32(header[0:4]) != SQUASHFS_MAGIC {
+		return 0, fmt.Errorf("%s is not a squashfs image", file.Name())
+	}
+	return int64(binary.LittleEndian.Uint64(header[40:48])), nil
+}
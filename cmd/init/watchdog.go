@@ -0,0 +1,165 @@
+/*
+ * Copyright (c) 2025 Manjeet Singh <itsmanjeet1998@gmail.com>.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 3.
+ *
+ * This program is distributed in the hope that it will be useful, but
+ * WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+ * General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+package main
+
+import (
+	"log"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"chillos/pkg/connect"
+	"chillos/pkg/kernel/watchdog"
+)
+
+const (
+	DEFAULT_WATCHDOG_TIMEOUT  = 30
+	SHUTDOWN_WATCHDOG_TIMEOUT = 30
+
+	SERVICE_MANAGER_CONTROL      = "service"
+	SERVICE_MANAGER_PING_TIMEOUT = 5 * time.Second
+	// the control socket only shows up once services are loaded
+	SERVICE_MANAGER_GRACE = 60 * time.Second
+)
+
+var (
+	watchdogDevice  string
+	watchdogTimeout int
+)
+
+// supervisor keeps the watchdog alive for as long as the service manager
+// answers on its control socket.
+type supervisor struct {
+	device   *watchdog.Watchdog
+	timeout  time.Duration
+	interval time.Duration
+
+	healthy  atomic.Int64 // unix nanoseconds of the last successful ping
+	checking atomic.Bool
+	stopped  atomic.Bool
+	starving bool
+}
+
+// startWatchdog opens the watchdog device configured on the kernel
+// cmdline, a nil supervisor is returned when it is disabled or missing.
+func startWatchdog() *supervisor {
+	if watchdogDevice == "" || watchdogTimeout <= 0 {
+		return nil
+	}
+	if _, err := os.Stat(watchdogDevice); err != nil {
+		log.Printf("no watchdog available: %v", err)
+		return nil
+	}
+
+	device, err := watchdog.Open(watchdogDevice)
+	if err != nil {
+		log.Println(err)
+		return nil
+	}
+
+	timeout, err := device.SetTimeout(watchdogTimeout)
+	if err != nil {
+		log.Printf("failed to set watchdog timeout, using driver default: %v", err)
+		if timeout, err = device.Timeout(); err != nil || timeout <= 0 {
+			timeout = watchdogTimeout
+		}
+	}
+	log.Printf("watchdog %s armed with %ds timeout", watchdogDevice, timeout)
+
+	s := &supervisor{
+		device:   device,
+		timeout:  time.Duration(timeout) * time.Second,
+		interval: time.Duration(timeout) * time.Second / 3,
+	}
+	s.healthy.Store(time.Now().Add(SERVICE_MANAGER_GRACE).UnixNano())
+	s.pet()
+	return s
+}
+
+// ticker returns the channel the main loop pets the watchdog on, nil
+// blocks forever so a disabled watchdog costs nothing.
+func (s *supervisor) ticker() <-chan time.Time {
+	if s == nil {
+		return nil
+	}
+	return time.NewTicker(s.interval).C
+}
+
+// tick pets the watchdog if the service manager answered recently and
+// starts the next health check in the background.
+func (s *supervisor) tick() {
+	if s == nil || s.stopped.Load() {
+		return
+	}
+
+	if s.checking.CompareAndSwap(false, true) {
+		go func() {
+			defer s.checking.Store(false)
+			if err := pingServiceManager(); err != nil {
+				return
+			}
+			s.healthy.Store(time.Now().UnixNano())
+		}()
+	}
+
+	if time.Since(time.Unix(0, s.healthy.Load())) > s.timeout {
+		if !s.starving {
+			log.Println("service manager is not responding, letting the watchdog expire")
+			s.starving = true
+		}
+		return
+	}
+	if s.starving {
+		log.Println("service manager is responding again")
+		s.starving = false
+	}
+	s.pet()
+}
+
+func (s *supervisor) pet() {
+	if err := s.device.Keepalive(); err != nil {
+		log.Printf("failed to pet watchdog: %v", err)
+	}
+}
+
+// arm stops the health checks and gives the shutdown sequence a final
+// timeout, if unmounting hangs the machine still resets.
+func (s *supervisor) arm() {
+	if s == nil || !s.stopped.CompareAndSwap(false, true) {
+		return
+	}
+
+	if _, err := s.device.SetTimeout(SHUTDOWN_WATCHDOG_TIMEOUT); err != nil {
+		log.Printf("failed to set shutdown watchdog timeout: %v", err)
+	}
+	s.pet()
+}
+
+func pingServiceManager() error {
+	conn, err := connect.Connect(SERVICE_MANAGER_CONTROL)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err := conn.SetDeadline(time.Now().Add(SERVICE_MANAGER_PING_TIMEOUT)); err != nil {
+		return err
+	}
+
+	var status map[string]int
+	return conn.Send("ping", nil, &status)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"chillos/pkg/kernel/module"
+)
+
+// list prints the loaded modules like lsmod, -parameters adds their
+// current parameter values.
+func list(args []string) error {
+	flags := flag.NewFlagSet("list", flag.ExitOnError)
+	parameters := flags.Bool("parameters", false, "Print module parameters")
+	_ = flags.Parse(args)
+
+	modules, err := module.List()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "Module\tSize\tUsed\tBy\tState\tTaint")
+	for _, m := range modules {
+		used := "-"
+		if m.RefCount >= 0 {
+			used = fmt.Sprint(m.RefCount)
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s\n", m.Name, m.Size, used,
+			strings.Join(m.Users(), ","), m.State, m.Taint)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if !*parameters {
+		return nil
+	}
+
+	for _, m := range modules {
+		if len(m.Parameters) == 0 {
+			continue
+		}
+		fmt.Printf("\n%s:\n", m.Name)
+		names := make([]string, 0, len(m.Parameters))
+		for name := range m.Parameters {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			fmt.Printf("  %s=%s\n", name, m.Parameters[name])
+		}
+	}
+	return nil
+}
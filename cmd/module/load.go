@@ -2,13 +2,26 @@ package main
 
 import (
 	"fmt"
+	"path/filepath"
+	"strings"
 
 	"chillos/pkg/kernel/module"
 )
 
+// load loads a module and its dependencies, explicitly loaded modules are
+// not subject to the blacklist.
 func load(args []string) error {
-	if len(args) != 1 {
+	if len(args) == 0 {
 		return fmt.Errorf("no module provided")
 	}
-	return module.Load(args[0], modulesPath, map[string]bool{})
+
+	config, err := module.LoadConfig(filepath.Join(root, module.ConfigPath))
+	if err != nil {
+		return err
+	}
+	if root == "/" {
+		_ = config.ReadCmdline(module.CmdlinePath)
+	}
+
+	return module.Load(args[0], strings.Join(args[1:], " "), modulesPath, config, map[string]bool{})
 }
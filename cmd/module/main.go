@@ -18,6 +18,7 @@ var (
 		"unload": unload,
 		"info":   info,
 		"cache":  cache,
+		"list":   list,
 	}
 )
 
@@ -1,14 +1,25 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 
 	"chillos/pkg/kernel/module"
 )
 
 func unload(args []string) error {
-	if len(args) != 1 {
+	flags := flag.NewFlagSet("unload", flag.ExitOnError)
+	recursive := flags.Bool("r", false, "Also unload the modules it used when nothing else uses them")
+	_ = flags.Parse(args)
+
+	if flags.NArg() == 0 {
 		return fmt.Errorf("no module provided")
 	}
-	return module.Delete(args[0], 0)
+
+	for _, name := range flags.Args() {
+		if err := module.Unload(name, *recursive); err != nil {
+			return err
+		}
+	}
+	return nil
 }
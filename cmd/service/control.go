@@ -0,0 +1,175 @@
+/*
+ * Copyright (c) 2025 Manjeet Singh <itsmanjeet1998@gmail.com>.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 3.
+ *
+ * This program is distributed in the hope that it will be useful, but
+ * WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+ * General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+
+	"chillos/pkg/connect"
+)
+
+const (
+	// ControlID is the socket the service manager answers on, init pings
+	// it to decide whether to keep petting the watchdog.
+	ControlID = "service"
+)
+
+type Status struct {
+	Services int `json:"services"`
+	Running  int `json:"running"`
+	Failed   int `json:"failed"`
+}
+
+// Request names the service a start or stop command applies to.
+type Request struct {
+	Name string `json:"name"`
+}
+
+type Reply struct {
+	Error string `json:"error,omitempty"`
+}
+
+type controlServer struct{}
+
+func listenControl() {
+	if err := connect.Listen(ControlID, &controlServer{}); err != nil {
+		log.Printf("failed to listen on control socket: %v", err)
+	}
+}
+
+func (c *controlServer) Handle(conn *connect.Connection) {
+	go func() {
+		defer conn.Close()
+		for {
+			cmd, payload, err := conn.Receive()
+			if err != nil {
+				if err != io.EOF {
+					log.Printf("control: %v", err)
+				}
+				return
+			}
+
+			switch cmd {
+			case "ping":
+				if err := conn.Send("pong", status(), nil); err != nil {
+					return
+				}
+			case "start", "stop":
+				var req Request
+				var reply Reply
+				if err := json.Unmarshal(payload, &req); err != nil {
+					reply.Error = err.Error()
+				} else if cmd == "start" {
+					reply.Error = errorString(startService(req.Name))
+				} else {
+					reply.Error = errorString(stopService(req.Name))
+				}
+				if err := conn.Send(cmd, reply, nil); err != nil {
+					return
+				}
+			default:
+				log.Printf("control: unknown command %q", cmd)
+				return
+			}
+		}
+	}()
+}
+
+func status() Status {
+	var s Status
+	foreachService(func(service *Service) {
+		s.Services++
+		switch service.State {
+		case Running:
+			s.Running++
+		case Failed:
+			s.Failed++
+		}
+	})
+	return s
+}
+
+// startService starts name on request, template instances are created
+// on first use so that e.g. udev rules can start display@tty2.
+func startService(name string) error {
+	if isShuttingDown {
+		return fmt.Errorf("shutting down")
+	}
+
+	// lookup, creation and the running check are one step, concurrent
+	// requests must neither add an instance twice nor start it twice
+	servicesMutex.Lock()
+	s := findService(name)
+	if s == nil {
+		var err error
+		if s, err = NewServiceInstance(ServicesPath, name); err != nil {
+			servicesMutex.Unlock()
+			return fmt.Errorf("unknown service %s: %v", name, err)
+		}
+		services = append(services, s)
+	}
+
+	if s.Kind == Mount || s.starting || s.isProcessRunning() {
+		servicesMutex.Unlock()
+		return nil
+	}
+	s.starting = true
+	s.stopped = false
+	servicesMutex.Unlock()
+
+	go func() {
+		defer func() {
+			servicesMutex.Lock()
+			s.starting = false
+			servicesMutex.Unlock()
+		}()
+
+		if err := waitForDepends(s); err != nil {
+			log.Printf("dependencies not met for %s: %v", s.Name, err)
+			s.State = Failed
+			return
+		}
+		runService(s)
+	}()
+	return nil
+}
+
+func stopService(name string) error {
+	s := getService(name)
+	if s == nil {
+		return fmt.Errorf("unknown service %s", name)
+	}
+	if s.Kind == Mount {
+		return fmt.Errorf("%s can not be stopped", name)
+	}
+
+	s.stopped = true
+	if !s.isProcessRunning() && s.ExecStop == "" {
+		return nil
+	}
+	return s.Stop(journal)
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
@@ -22,12 +22,14 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"slices"
 	"sync"
 	"time"
 )
 
 var (
 	services       []*Service
+	servicesMutex  sync.RWMutex
 	waitGroup      sync.WaitGroup
 	journal        *os.File
 	isShuttingDown bool
@@ -53,13 +55,28 @@ func loadServices(path string) error {
 		}
 
 		if !service.isTemplate {
-			services = append(services, service)
+			addService(service)
 		}
 	}
 	return nil
 }
 
+func addService(service *Service) {
+	servicesMutex.Lock()
+	defer servicesMutex.Unlock()
+
+	services = append(services, service)
+}
+
 func getService(id string) *Service {
+	servicesMutex.RLock()
+	defer servicesMutex.RUnlock()
+
+	return findService(id)
+}
+
+// findService is getService for callers holding servicesMutex.
+func findService(id string) *Service {
 	for _, service := range services {
 		if service.Name == id {
 			return service
@@ -69,7 +86,11 @@ func getService(id string) *Service {
 }
 
 func foreachService(f func(s *Service)) {
-	for _, service := range services {
+	servicesMutex.RLock()
+	snapshot := slices.Clone(services)
+	servicesMutex.RUnlock()
+
+	for _, service := range snapshot {
 		f(service)
 	}
 }
@@ -98,7 +119,29 @@ func triggerStage(stage string) {
 	stageWaitGroup.Wait()
 }
 
+// failedServices returns the services of stage that did not reach
+// Running or Finished.
+func failedServices(stage string) []string {
+	var failed []string
+	foreachService(func(s *Service) {
+		if s.Stage != stage {
+			return
+		}
+		switch s.State {
+		case Running, Finished:
+		default:
+			failed = append(failed, s.Name)
+		}
+	})
+	return failed
+}
+
 func runService(s *Service) {
+	if s.Kind == Mount {
+		runMount(s)
+		return
+	}
+
 	for {
 		log.Printf("Starting service: %s", s.Name)
 
@@ -155,7 +198,7 @@ func monitorDaemonService(s *Service) {
 			s.State = Finished
 		}
 
-		if isShuttingDown || !s.Restart {
+		if isShuttingDown || s.stopped || !s.Restart {
 			break
 		}
 
@@ -0,0 +1,169 @@
+/*
+ * Copyright (c) 2025 Manjeet Singh <itsmanjeet1998@gmail.com>.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 3.
+ *
+ * This program is distributed in the hope that it will be useful, but
+ * WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+ * General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"chillos/pkg/kernel/block"
+	"chillos/pkg/kernel/mount"
+)
+
+const (
+	MountsPath = "/config/mounts"
+
+	DEFAULT_MOUNT_TIMEOUT = 10
+)
+
+// MountPoint describes one entry of /config/mounts, a JSON list of mounts
+// performed during the "mount" stage. Each entry becomes a "<name>.mount"
+// service which others can depend on, a failed nofail mount still lets
+// them start.
+//
+//	[{"name": "scratch", "source": "LABEL=scratch", "target": "/scratch",
+//	  "type": "ext4", "options": ["nosuid", "nodev"], "nofail": true}]
+type MountPoint struct {
+	Name    string   `json:"name"`
+	Source  string   `json:"source"`
+	Target  string   `json:"target"`
+	Type    string   `json:"type"`
+	Options []string `json:"options"`
+	Depends []string `json:"depends"`
+	NoFail  bool     `json:"nofail"`
+	Timeout int      `json:"timeout"`
+}
+
+func loadMounts(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	var mounts []*MountPoint
+	if err := json.Unmarshal(data, &mounts); err != nil {
+		return fmt.Errorf("invalid mounts %s: %v", path, err)
+	}
+
+	for _, m := range mounts {
+		if m.Target == "" {
+			log.Printf("ignoring mount of %s without target", m.Source)
+			continue
+		}
+		if m.Name == "" {
+			m.Name = mountName(m.Target)
+		}
+		if m.Timeout == 0 {
+			m.Timeout = DEFAULT_MOUNT_TIMEOUT
+		}
+
+		addService(&Service{
+			Stage:       "mount",
+			Kind:        Mount,
+			Description: fmt.Sprintf("mount %s on %s", m.Source, m.Target),
+			Depends:     m.Depends,
+			Name:        m.Name + ".mount",
+			State:       NotStarted,
+			mount:       m,
+		})
+	}
+	return nil
+}
+
+func runMount(s *Service) {
+	if s.mount == nil {
+		log.Printf("mount service %s has no mount point", s.Name)
+		s.State = Failed
+		return
+	}
+
+	log.Printf("Mounting %s on %s", s.mount.Source, s.mount.Target)
+	if err := s.mount.Do(); err != nil {
+		if s.mount.NoFail {
+			// the mount is optional, nothing depending on it has to wait
+			log.Printf("skipping optional mount %s: %v", s.Name, err)
+			s.State = Finished
+		} else {
+			log.Printf("failed to mount %s: %v", s.Name, err)
+			s.State = Failed
+		}
+		return
+	}
+	s.State = Finished
+}
+
+// mountName derives a service name from target, /var/lib becomes
+// var-lib and / becomes root.
+func mountName(target string) string {
+	name := strings.Trim(strings.ReplaceAll(target, "/", "-"), "-")
+	if name == "" {
+		return "root"
+	}
+	return name
+}
+
+func (m *MountPoint) Do() error {
+	if mounted, err := mount.IsMounted(m.Target); err == nil && mounted {
+		return nil
+	}
+
+	source := m.Source
+	if m.needsDevice() {
+		var err error
+		if source, err = m.waitForDevice(); err != nil {
+			return err
+		}
+	}
+
+	return mount.Mount(source, m.Target, m.Type, m.Options)
+}
+
+func (m *MountPoint) needsDevice() bool {
+	for _, option := range m.Options {
+		if option == "bind" || option == "rbind" {
+			return false
+		}
+	}
+	return strings.HasPrefix(m.Source, "/dev/") ||
+		strings.HasPrefix(m.Source, "UUID=") ||
+		strings.HasPrefix(m.Source, "LABEL=")
+}
+
+// waitForDevice resolves the mount source, giving devices which are still
+// being probed by the kernel some time to appear. Optional mounts are
+// only tried once.
+func (m *MountPoint) waitForDevice() (string, error) {
+	deadline := time.Now().Add(time.Duration(m.Timeout) * time.Second)
+	for {
+		device, err := block.Find(m.Source)
+		if err == nil {
+			return device, nil
+		}
+
+		if m.NoFail || time.Now().After(deadline) {
+			return "", err
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
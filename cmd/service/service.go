@@ -34,6 +34,7 @@ type Kind string
 const (
 	Oneshot Kind = "oneshot"
 	Daemon  Kind = "daemon"
+	Mount   Kind = "mount"
 )
 
 type State int
@@ -68,7 +69,10 @@ type Service struct {
 	Process    *os.Process `json:"-"`
 	State      State       `json:"-"`
 	isTemplate bool
+	stopped    bool
+	starting   bool // a start request is in progress, guarded by servicesMutex
 	tty        *os.File
+	mount      *MountPoint
 }
 
 func NewService(filename string) (*Service, error) {
@@ -77,14 +81,33 @@ func NewService(filename string) (*Service, error) {
 		return nil, err
 	}
 
+	name := strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+	return parseService(data, name)
+}
+
+// NewServiceInstance instantiates name, e.g. display@tty2, from the
+// template service file of the same prefix in path.
+func NewServiceInstance(path, name string) (*Service, error) {
+	idx := strings.Index(name, "@")
+	if idx == -1 || idx == len(name)-1 {
+		return nil, fmt.Errorf("%s is not a template instance", name)
+	}
+
+	data, err := os.ReadFile(filepath.Join(path, name[:idx+1]+".service"))
+	if err != nil {
+		return nil, err
+	}
+	return parseService(data, name)
+}
+
+func parseService(data []byte, name string) (*Service, error) {
 	// TODO: Fix this
-	base := strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
 	var isTemplate bool
-	if idx := strings.Index(base, "@"); idx != -1 {
-		if idx == len(base)-1 {
+	if idx := strings.Index(name, "@"); idx != -1 {
+		if idx == len(name)-1 {
 			isTemplate = true
 		} else {
-			data = []byte(strings.ReplaceAll(string(data), "%i", base[idx+1:]))
+			data = []byte(strings.ReplaceAll(string(data), "%i", name[idx+1:]))
 		}
 	}
 	var service Service
@@ -97,8 +120,7 @@ func NewService(filename string) (*Service, error) {
 	if service.Kind == "" {
 		service.Kind = Daemon
 	}
-	service.Name = filepath.Base(filename)
-	service.Name = strings.TrimSuffix(service.Name, filepath.Ext(service.Name))
+	service.Name = name
 
 	service.State = NotStarted
 	service.isTemplate = isTemplate
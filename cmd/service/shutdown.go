@@ -21,12 +21,24 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strings"
 	"syscall"
+
+	"chillos/pkg/kernel/kexec"
+)
+
+const (
+	DEFAULT_KEXEC_KERNEL = "/boot/kernel.img"
+	DEFAULT_KEXEC_INITRD = "/boot/initramfs.img"
 )
 
 func shutdown(args []string) error {
 	f := flag.NewFlagSet("shutdown", flag.ContinueOnError)
 	reboot := f.Bool("reboot", false, "Reboot system")
+	soft := f.Bool("kexec", false, "Reboot into a new kernel without firmware reset")
+	kernel := f.String("kernel", DEFAULT_KEXEC_KERNEL, "Kernel to kexec into")
+	initrd := f.String("initrd", DEFAULT_KEXEC_INITRD, "Initramfs for the kexec kernel, empty for none")
+	cmdline := f.String("append", "", "Kernel command line, defaults to the current one")
 	if err := f.Parse(args); err != nil {
 		return err
 	}
@@ -40,5 +52,20 @@ func shutdown(args []string) error {
 	if *reboot {
 		s = syscall.SIGINT
 	}
+
+	if *soft {
+		if *cmdline == "" {
+			data, err := os.ReadFile("/proc/cmdline")
+			if err != nil {
+				return fmt.Errorf("failed to read current kernel cmdline %v", err)
+			}
+			*cmdline = strings.TrimSpace(string(data))
+		}
+
+		if err := kexec.Load(*kernel, *initrd, *cmdline); err != nil {
+			return fmt.Errorf("failed to stage kernel %v", err)
+		}
+		s = syscall.SIGUSR1
+	}
 	return init.Signal(s)
 }
@@ -18,12 +18,12 @@
 package main
 
 import (
-	"bufio"
 	"log"
 	"os"
 	"path/filepath"
-	"strconv"
 	"strings"
+
+	"chillos/pkg/boot"
 )
 
 const (
@@ -32,7 +32,7 @@ const (
 )
 
 var (
-	stages = []string{"pre-init", "init", "post-init"}
+	stages = []string{"pre-init", "mount", "init", "post-init"}
 )
 
 func startup(args []string) error {
@@ -44,7 +44,13 @@ func startup(args []string) error {
 		}
 	}
 
-	ensureRequiredDirs()
+	if err := provisionUsers(UsersPath); err != nil {
+		log.Printf("failed to provision users: %v", err)
+	}
+	ensureHomeDirs()
+	if err := applyTmpfiles(TmpfilesPath); err != nil {
+		log.Printf("failed to apply tmpfiles: %v", err)
+	}
 
 	var err error
 	journal, err = os.OpenFile(JournalPath, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
@@ -55,73 +61,43 @@ func startup(args []string) error {
 	}
 
 	loadServices(ServicesPath)
+	if err := loadMounts(MountsPath); err != nil {
+		log.Printf("failed to load mounts: %v", err)
+	}
+	go listenControl()
 
 	for _, stage := range stages {
 		triggerStage(stage)
 	}
 
 	triggerStage("service")
+	if failed := failedServices("service"); len(failed) != 0 {
+		log.Printf("not marking boot good, failed services: %s", strings.Join(failed, ", "))
+	} else {
+		markBootGood()
+	}
 
 	waitGroup.Wait()
 	return nil
 }
 
-func ensureRequiredDirs() {
-	const (
-		USERNAME_FIELD int = iota
-		X_FIELD
-		UID_FIELD
-		GID_FIELD
-		COMMENT_FIELD
-		HOME_DIR_FIELD
-		SHELL_FIELD
-	)
-
-	file, err := os.OpenFile("/etc/passwd", os.O_RDONLY, 0)
-	if err == nil {
-		scanner := bufio.NewScanner(file)
-		for scanner.Scan() {
-			line := scanner.Text()
-			user := strings.Split(line, ":")
-			if len(user) != 7 {
-				continue
-			}
-			username := user[USERNAME_FIELD]
-			homedir := user[HOME_DIR_FIELD]
-			// shell := user[SHELL_FIELD]
-			uid, err := strconv.Atoi(user[UID_FIELD])
-			if err != nil {
-				log.Printf("invalid user id for %s, %s", username, user[UID_FIELD])
-				continue
-			}
-
-			gid, err := strconv.Atoi(user[GID_FIELD])
-			if err != nil {
-				log.Printf("invalid groupd id for %s, %s", username, user[GID_FIELD])
-				continue
-			}
-
-			if _, err := os.Stat(homedir); err != nil {
-				if err := os.MkdirAll(filepath.Dir(homedir), 0755); err != nil {
-					log.Printf("failed to create homedir %s for %s %v", homedir, user, err)
-					continue
-				}
-
-				if err := os.Mkdir(homedir, 0750); err != nil {
-					log.Printf("failed to create homedir %s for %s %v", homedir, user, err)
-					continue
-				}
-
-				if err := os.Chown(homedir, uid, gid); err != nil {
-					log.Printf("failed to chown homedir %s for %s %v", homedir, user, err)
-					continue
-				}
-			}
+func markBootGood() {
+	state, err := boot.LoadState(boot.StatePath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("failed to load boot state: %v", err)
 		}
+		return
+	}
+
+	if state.Good {
+		return
 	}
-	defer file.Close()
 
-	for _, dir := range []string{"services", "log"} {
-		_ = os.MkdirAll(filepath.Join("/cache", dir), 0755)
+	state.MarkGood()
+	if err := state.Save(boot.StatePath); err != nil {
+		log.Printf("failed to mark slot %s good: %v", state.Active, err)
+		return
 	}
+	log.Printf("marked slot %s good", state.Active)
 }
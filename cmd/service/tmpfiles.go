@@ -0,0 +1,221 @@
+/*
+ * Copyright (c) 2025 Manjeet Singh <itsmanjeet1998@gmail.com>.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 3.
+ *
+ * This program is distributed in the hope that it will be useful, but
+ * WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+ * General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	TmpfilesPath = "/config/tmpfiles"
+)
+
+// Tmpfile is a single entry of /config/tmpfiles, a JSON list of paths that
+// are created, if missing, and fixed up on every boot.
+type Tmpfile struct {
+	Type    string `json:"type"` // directory, file, symlink, char or block
+	Path    string `json:"path"`
+	Mode    string `json:"mode"` // octal, e.g. "0755"
+	User    string `json:"user"`
+	Group   string `json:"group"`
+	Target  string `json:"target"`  // symlink target
+	Content string `json:"content"` // initial file content
+	Major   uint32 `json:"major"`
+	Minor   uint32 `json:"minor"`
+	Age     string `json:"age"` // remove directory entries older than this
+}
+
+func applyTmpfiles(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	var entries []Tmpfile
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return fmt.Errorf("invalid tmpfiles config %s: %v", path, err)
+	}
+
+	for _, entry := range entries {
+		if err := entry.Apply(); err != nil {
+			log.Printf("tmpfiles %s: %v", entry.Path, err)
+		}
+	}
+	return nil
+}
+
+func (t *Tmpfile) Apply() error {
+	if !filepath.IsAbs(t.Path) {
+		return fmt.Errorf("path must be absolute")
+	}
+
+	var mode os.FileMode = 0644
+	if t.Type == "directory" {
+		mode = 0755
+	}
+	if t.Mode != "" {
+		m, err := strconv.ParseUint(t.Mode, 8, 32)
+		if err != nil {
+			return fmt.Errorf("invalid mode %q", t.Mode)
+		}
+		mode = os.FileMode(m).Perm()
+		if m&syscall.S_ISUID != 0 {
+			mode |= os.ModeSetuid
+		}
+		if m&syscall.S_ISGID != 0 {
+			mode |= os.ModeSetgid
+		}
+		if m&syscall.S_ISVTX != 0 {
+			mode |= os.ModeSticky
+		}
+	}
+
+	uid, gid, err := t.owner()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(t.Path), 0755); err != nil {
+		return err
+	}
+
+	switch t.Type {
+	case "directory":
+		if err := os.MkdirAll(t.Path, mode); err != nil {
+			return err
+		}
+		if t.Age != "" {
+			age, err := parseAge(t.Age)
+			if err != nil {
+				return err
+			}
+			cleanupDirectory(t.Path, age)
+		}
+
+	case "file":
+		if _, err := os.Stat(t.Path); os.IsNotExist(err) {
+			if err := os.WriteFile(t.Path, []byte(t.Content), mode); err != nil {
+				return err
+			}
+		}
+
+	case "symlink":
+		if target, err := os.Readlink(t.Path); err == nil && target == t.Target {
+			return nil
+		}
+		_ = os.Remove(t.Path)
+		if err := os.Symlink(t.Target, t.Path); err != nil {
+			return err
+		}
+		return os.Lchown(t.Path, uid, gid)
+
+	case "char", "block":
+		kind := uint32(syscall.S_IFCHR)
+		if t.Type == "block" {
+			kind = syscall.S_IFBLK
+		}
+		if _, err := os.Stat(t.Path); os.IsNotExist(err) {
+			if err := syscall.Mknod(t.Path, kind|uint32(mode.Perm()), int(makedev(t.Major, t.Minor))); err != nil {
+				return err
+			}
+		}
+
+	default:
+		return fmt.Errorf("unknown type %q", t.Type)
+	}
+
+	// chmod and chown follow links, whatever they point to is not ours
+	if info, err := os.Lstat(t.Path); err != nil {
+		return err
+	} else if info.Mode()&os.ModeSymlink != 0 {
+		return fmt.Errorf("is a symlink, not changing mode or owner")
+	}
+
+	if err := os.Chmod(t.Path, mode); err != nil {
+		return err
+	}
+	return os.Chown(t.Path, uid, gid)
+}
+
+func (t *Tmpfile) owner() (int, int, error) {
+	uid, gid := 0, 0
+	if t.User != "" {
+		usr, err := user.Lookup(t.User)
+		if err != nil {
+			return 0, 0, err
+		}
+		uid, _ = strconv.Atoi(usr.Uid)
+		gid, _ = strconv.Atoi(usr.Gid)
+	}
+	if t.Group != "" {
+		grp, err := user.LookupGroup(t.Group)
+		if err != nil {
+			return 0, 0, err
+		}
+		gid, _ = strconv.Atoi(grp.Gid)
+	}
+	return uid, gid, nil
+}
+
+// parseAge accepts time.ParseDuration units plus d for days and w for weeks.
+func parseAge(s string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			v, err := strconv.Atoi(n)
+			if err != nil {
+				return 0, fmt.Errorf("invalid age %q", s)
+			}
+			return time.Duration(v) * unit, nil
+		}
+	}
+	return time.ParseDuration(s)
+}
+
+func cleanupDirectory(dir string, age time.Duration) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+
+	cutoff := time.Now().Add(-age)
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || info.ModTime().After(cutoff) {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(dir, entry.Name())); err != nil {
+			log.Printf("failed to clean %s: %v", filepath.Join(dir, entry.Name()), err)
+		}
+	}
+}
+
+func makedev(major, minor uint32) uint64 {
+	return uint64(minor&0xff) | uint64(major&0xfff)<<8 |
+		uint64(minor&^0xff)<<12 | uint64(major&^0xfff)<<32
+}
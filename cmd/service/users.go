@@ -0,0 +1,243 @@
+/*
+ * Copyright (c) 2025 Manjeet Singh <itsmanjeet1998@gmail.com>.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 3.
+ *
+ * This program is distributed in the hope that it will be useful, but
+ * WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+ * General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"chillos/pkg/users"
+)
+
+const (
+	UsersPath = "/config/users"
+)
+
+// UsersConfig describes /config/users, users and groups which are created
+// at boot when missing from /etc/passwd and /etc/group.
+//
+//	{"groups": [{"name": "input", "system": true}],
+//	 "users": [{"name": "display", "groups": ["input"], "system": true}]}
+type UsersConfig struct {
+	Groups []GroupConfig `json:"groups"`
+	Users  []UserConfig  `json:"users"`
+}
+
+type GroupConfig struct {
+	Name   string `json:"name"`
+	GID    *int   `json:"gid"`
+	System bool   `json:"system"`
+}
+
+type UserConfig struct {
+	Name    string   `json:"name"`
+	UID     *int     `json:"uid"`
+	Group   string   `json:"group"`
+	Groups  []string `json:"groups"`
+	Comment string   `json:"comment"`
+	Home    string   `json:"home"`
+	Shell   string   `json:"shell"`
+	System  bool     `json:"system"`
+}
+
+func idRange(system bool) (int, int) {
+	if system {
+		return users.SystemMinID, users.SystemMaxID
+	}
+	return users.UserMinID, users.UserMaxID
+}
+
+func provisionUsers(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	var config UsersConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return fmt.Errorf("invalid users config %s: %v", path, err)
+	}
+
+	passwd, err := users.ReadPasswd(users.PasswdPath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	groups, err := users.ReadGroup(users.GroupPath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	usedUIDs, usedGIDs := map[int]bool{}, map[int]bool{}
+	for _, u := range passwd {
+		usedUIDs[u.UID] = true
+	}
+	for _, g := range groups {
+		usedGIDs[g.GID] = true
+	}
+
+	addGroup := func(name string, gid *int, system bool) (users.Group, error) {
+		if g, ok := users.LookupGroup(groups, name); ok {
+			return g, nil
+		}
+
+		g := users.Group{Name: name, Password: "x"}
+		if gid != nil {
+			g.GID = *gid
+		} else {
+			min, max := idRange(system)
+			if g.GID, err = users.FreeID(usedGIDs, min, max); err != nil {
+				return g, err
+			}
+		}
+
+		log.Printf("creating group %s (%d)", g.Name, g.GID)
+		usedGIDs[g.GID] = true
+		groups = append(groups, g)
+		return g, nil
+	}
+
+	var created []string
+	changed := false
+	for _, gc := range config.Groups {
+		if _, ok := users.LookupGroup(groups, gc.Name); ok {
+			continue
+		}
+		if _, err := addGroup(gc.Name, gc.GID, gc.System); err != nil {
+			return fmt.Errorf("group %s: %v", gc.Name, err)
+		}
+		changed = true
+	}
+
+	for _, uc := range config.Users {
+		if _, ok := users.LookupUser(passwd, uc.Name); !ok {
+			primary := uc.Group
+			if primary == "" {
+				primary = uc.Name
+			}
+			if _, ok := users.LookupGroup(groups, primary); !ok {
+				changed = true
+			}
+			group, err := addGroup(primary, nil, uc.System)
+			if err != nil {
+				return fmt.Errorf("user %s: %v", uc.Name, err)
+			}
+
+			u := users.User{
+				Name:     uc.Name,
+				Password: "x",
+				GID:      group.GID,
+				Comment:  uc.Comment,
+				Home:     uc.Home,
+				Shell:    uc.Shell,
+			}
+			if uc.UID != nil {
+				u.UID = *uc.UID
+			} else {
+				min, max := idRange(uc.System)
+				if u.UID, err = users.FreeID(usedUIDs, min, max); err != nil {
+					return fmt.Errorf("user %s: %v", uc.Name, err)
+				}
+			}
+			if u.Home == "" {
+				u.Home = "/"
+			}
+
+			log.Printf("creating user %s (%d)", u.Name, u.UID)
+			usedUIDs[u.UID] = true
+			passwd = append(passwd, u)
+			created = append(created, u.Name)
+			changed = true
+		}
+
+		for _, name := range uc.Groups {
+			idx := slices.IndexFunc(groups, func(g users.Group) bool { return g.Name == name })
+			if idx == -1 {
+				log.Printf("user %s: supplementary group %s does not exist", uc.Name, name)
+				continue
+			}
+			if !slices.Contains(groups[idx].Members, uc.Name) {
+				groups[idx].Members = append(groups[idx].Members, uc.Name)
+				changed = true
+			}
+		}
+	}
+
+	if !changed {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(users.GroupPath), 0755); err != nil {
+		return err
+	}
+	if err := users.WriteGroup(users.GroupPath, groups); err != nil {
+		return err
+	}
+	if err := users.WritePasswd(users.PasswdPath, passwd); err != nil {
+		return err
+	}
+
+	if len(created) == 0 {
+		return nil
+	}
+	shadows, err := users.ReadShadow(users.ShadowPath)
+	if err != nil {
+		// systems without shadow passwords keep working with "x"
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	for _, name := range created {
+		shadows = append(shadows, users.Shadow{Name: name, Hash: "!"})
+	}
+	return users.WriteShadow(users.ShadowPath, shadows)
+}
+
+func ensureHomeDirs() {
+	passwd, err := users.ReadPasswd(users.PasswdPath)
+	if err != nil {
+		log.Printf("failed to read users: %v", err)
+		return
+	}
+
+	for _, u := range passwd {
+		if _, err := os.Stat(u.Home); err == nil {
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(u.Home), 0755); err != nil {
+			log.Printf("failed to create homedir %s for %s %v", u.Home, u.Name, err)
+			continue
+		}
+
+		if err := os.Mkdir(u.Home, 0750); err != nil {
+			log.Printf("failed to create homedir %s for %s %v", u.Home, u.Name, err)
+			continue
+		}
+
+		if err := os.Chown(u.Home, u.UID, u.GID); err != nil {
+			log.Printf("failed to chown homedir %s for %s %v", u.Home, u.Name, err)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+/*
+ * Copyright (c) 2025 Manjeet Singh <itsmanjeet1998@gmail.com>.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 3.
+ *
+ * This program is distributed in the hope that it will be useful, but
+ * WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+ * General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+
+	"chillos/pkg/udev"
+)
+
+const (
+	SYSFS_PATH = "/sys"
+
+	// attributes larger than this are binary blobs nobody wants printed
+	MAX_ATTRIBUTE_SIZE = 4096
+)
+
+// info prints what udevd recorded for a device followed by the sysfs
+// attributes of the device and all of its parents.
+func info(args []string) error {
+	flags := flag.NewFlagSet("info", flag.ExitOnError)
+	attributes := flags.Bool("attributes", true, "Print sysfs attributes of the device and its parents")
+	_ = flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		return fmt.Errorf("no device provided")
+	}
+
+	devpath, err := resolveDevice(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("P: %s\n", devpath)
+	if e, err := udev.ReadDevice(devpath); err == nil {
+		if e.DeviceNode != "" {
+			fmt.Printf("N: %s\n", e.DeviceNode)
+		}
+		for _, link := range e.Symlinks {
+			fmt.Printf("S: %s\n", link)
+		}
+		printProperties(e)
+	} else {
+		fmt.Println("(not in the udev database, showing kernel properties)")
+		data, err := os.ReadFile(filepath.Join(SYSFS_PATH, devpath, "uevent"))
+		if err != nil {
+			return err
+		}
+		for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+			fmt.Printf("E: %s\n", line)
+		}
+	}
+
+	if !*attributes {
+		return nil
+	}
+
+	for dir := devpath; dir != "/devices" && dir != "/" && dir != "."; dir = filepath.Dir(dir) {
+		// intermediate class and bus directories are not devices
+		if _, err := os.Stat(filepath.Join(SYSFS_PATH, dir, "uevent")); err != nil {
+			continue
+		}
+		fmt.Println()
+		printAttributes(dir)
+	}
+	return nil
+}
+
+// resolveDevice accepts a device path with or without /sys, a device
+// node or a symlink to one and returns the device path.
+func resolveDevice(name string) (string, error) {
+	if strings.HasPrefix(name, SYSFS_PATH+"/") {
+		name = strings.TrimPrefix(name, SYSFS_PATH)
+	}
+	if strings.HasPrefix(name, "/devices/") {
+		if _, err := os.Stat(filepath.Join(SYSFS_PATH, name)); err != nil {
+			return "", err
+		}
+		return name, nil
+	}
+
+	if !strings.HasPrefix(name, "/") {
+		name = "/dev/" + name
+	}
+
+	var stat syscall.Stat_t
+	if err := syscall.Stat(name, &stat); err != nil {
+		return "", fmt.Errorf("%s: %w", name, err)
+	}
+
+	kind := "char"
+	switch stat.Mode & syscall.S_IFMT {
+	case syscall.S_IFBLK:
+		kind = "block"
+	case syscall.S_IFCHR:
+	default:
+		return "", fmt.Errorf("%s is not a device node", name)
+	}
+
+	major := (stat.Rdev >> 8 & 0xfff) | (stat.Rdev >> 32 &^ 0xfff)
+	minor := (stat.Rdev & 0xff) | (stat.Rdev >> 12 &^ 0xff)
+	link := filepath.Join(SYSFS_PATH, "dev", kind, fmt.Sprintf("%d:%d", major, minor))
+	target, err := filepath.EvalSymlinks(link)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimPrefix(target, SYSFS_PATH), nil
+}
+
+func printProperties(e udev.Event) {
+	fmt.Printf("E: ACTION=%s\n", e.Action)
+	fmt.Printf("E: DEVPATH=%s\n", e.DevicePath)
+	fmt.Printf("E: SUBSYSTEM=%s\n", e.Subsystem)
+	if e.DeviceName != "" {
+		fmt.Printf("E: DEVNAME=%s\n", e.DeviceName)
+	}
+
+	keys := make([]string, 0, len(e.Properties))
+	for key := range e.Properties {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		fmt.Printf("E: %s=%s\n", key, e.Properties[key])
+	}
+}
+
+func printAttributes(devpath string) {
+	dir := filepath.Join(SYSFS_PATH, devpath)
+	fmt.Printf("  looking at %s:\n", devpath)
+
+	if target, err := os.Readlink(filepath.Join(dir, "subsystem")); err == nil {
+		fmt.Printf("    SUBSYSTEM==%q\n", filepath.Base(target))
+	}
+	if target, err := os.Readlink(filepath.Join(dir, "driver")); err == nil {
+		fmt.Printf("    DRIVER==%q\n", filepath.Base(target))
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || entry.Name() == "uevent" {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || info.Mode().Perm()&0444 == 0 || info.Size() > MAX_ATTRIBUTE_SIZE {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil || len(data) > MAX_ATTRIBUTE_SIZE {
+			continue
+		}
+		value := strings.TrimRight(string(data), "\n")
+		if strings.ContainsRune(value, 0) || strings.Contains(value, "\n") {
+			continue
+		}
+		fmt.Printf("    ATTR{%s}==%q\n", entry.Name(), value)
+	}
+}
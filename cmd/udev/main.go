@@ -0,0 +1,59 @@
+/*
+ * Copyright (c) 2025 Manjeet Singh <itsmanjeet1998@gmail.com>.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 3.
+ *
+ * This program is distributed in the hope that it will be useful, but
+ * WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+ * General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+var (
+	commands = map[string]func([]string) error{
+		"info":    info,
+		"monitor": monitor,
+		"trigger": trigger,
+		"settle":  settle,
+	}
+)
+
+func init() {
+	flag.Usage = func() {
+		fmt.Printf("Usage: %s <info|monitor|trigger|settle> [OPTIONS]...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+}
+
+func main() {
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		return
+	}
+
+	cmd, ok := commands[flag.Arg(0)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "invalid command %v\n", flag.Arg(0))
+		os.Exit(1)
+	}
+
+	if err := cmd(flag.Args()[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,144 @@
+/*
+ * Copyright (c) 2025 Manjeet Singh <itsmanjeet1998@gmail.com>.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 3.
+ *
+ * This program is distributed in the hope that it will be useful, but
+ * WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+ * General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"chillos/pkg/udev"
+)
+
+const (
+	BUFFER_SIZE = 8192
+
+	// multicast group the kernel sends uevents to
+	KERNEL_GROUP = 1
+)
+
+// monitor prints the raw kernel uevents and the events as processed by
+// udevd, side by side, until interrupted.
+func monitor(args []string) error {
+	flags := flag.NewFlagSet("monitor", flag.ExitOnError)
+	kernel := flags.Bool("kernel", true, "Print kernel uevents")
+	processed := flags.Bool("udev", true, "Print events processed by udevd")
+	properties := flags.Bool("property", false, "Print event properties")
+	subsystem := flags.String("subsystem", "", "Comma separated subsystems to print")
+	_ = flags.Parse(args)
+
+	var subsystems []string
+	if *subsystem != "" {
+		subsystems = strings.Split(*subsystem, ",")
+	}
+
+	show := func(source string, e udev.Event) {
+		if len(subsystems) > 0 && !slices.Contains(subsystems, e.Subsystem) {
+			return
+		}
+		fmt.Printf("%-6s [%s] %-7s %s (%s)\n", source, time.Now().Format("15:04:05.000"), e.Action, e.DevicePath, e.Subsystem)
+		if *properties {
+			printProperties(e)
+			for _, link := range e.Symlinks {
+				fmt.Printf("S: %s\n", link)
+			}
+			fmt.Println()
+		}
+	}
+
+	errs := make(chan error, 2)
+	if *kernel {
+		go func() { errs <- monitorKernel(func(e udev.Event) { show("KERNEL", e) }) }()
+	}
+	if *processed {
+		go func() { errs <- monitorUdev(subsystems, func(e udev.Event) { show("UDEV", e) }) }()
+	}
+	if !*kernel && !*processed {
+		return nil
+	}
+	return <-errs
+}
+
+func monitorKernel(handle func(udev.Event)) error {
+	socket, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_RAW, syscall.NETLINK_KOBJECT_UEVENT)
+	if err != nil {
+		return fmt.Errorf("failed to create socket: %v", err)
+	}
+	defer syscall.Close(socket)
+
+	// pid 0 lets the kernel pick, udevd already owns our pid's address
+	if err := syscall.Bind(socket, &syscall.SockaddrNetlink{
+		Family: syscall.AF_NETLINK,
+		Groups: KERNEL_GROUP,
+	}); err != nil {
+		return fmt.Errorf("failed to bind to socket: %v", err)
+	}
+
+	buffer := make([]byte, BUFFER_SIZE)
+	for {
+		n, _, err := syscall.Recvfrom(socket, buffer, 0)
+		if err != nil {
+			if err == syscall.EINTR || err == syscall.ENOBUFS {
+				continue
+			}
+			return err
+		}
+		handle(udev.ParseUevent(buffer[:n]))
+	}
+}
+
+func monitorUdev(subsystems []string, handle func(udev.Event)) error {
+	m, err := udev.NewMonitor(subsystems...)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	// the monitor is meant for poll loops, wait on the socket ourselves
+	fds := []syscall.EpollEvent{{Events: syscall.EPOLLIN, Fd: int32(m.Fd())}}
+	epfd, err := syscall.EpollCreate1(0)
+	if err != nil {
+		return err
+	}
+	defer syscall.Close(epfd)
+	if err := syscall.EpollCtl(epfd, syscall.EPOLL_CTL_ADD, m.Fd(), &fds[0]); err != nil {
+		return err
+	}
+
+	for {
+		if _, err := syscall.EpollWait(epfd, fds, -1); err != nil {
+			if err == syscall.EINTR {
+				continue
+			}
+			return err
+		}
+
+		ev, err := m.Read()
+		if err != nil {
+			if err == syscall.EAGAIN {
+				continue
+			}
+			fmt.Fprintln(os.Stderr, "udevd went away")
+			return err
+		}
+		handle(ev.(udev.Event))
+	}
+}
@@ -0,0 +1,37 @@
+/*
+ * Copyright (c) 2025 Manjeet Singh <itsmanjeet1998@gmail.com>.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 3.
+ *
+ * This program is distributed in the hope that it will be useful, but
+ * WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+ * General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+package main
+
+import (
+	"flag"
+	"time"
+
+	"chillos/pkg/udev"
+)
+
+const (
+	DEFAULT_SETTLE_TIMEOUT = 120 * time.Second
+)
+
+// settle waits until udevd processed every queued event.
+func settle(args []string) error {
+	flags := flag.NewFlagSet("settle", flag.ExitOnError)
+	timeout := flags.Duration("timeout", DEFAULT_SETTLE_TIMEOUT, "Maximum time to wait")
+	_ = flags.Parse(args)
+
+	return udev.Settle(*timeout)
+}
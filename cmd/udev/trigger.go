@@ -0,0 +1,84 @@
+/*
+ * Copyright (c) 2025 Manjeet Singh <itsmanjeet1998@gmail.com>.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 3.
+ *
+ * This program is distributed in the hope that it will be useful, but
+ * WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+ * General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"chillos/pkg/udev"
+)
+
+// trigger asks the kernel to replay uevents for existing devices.
+func trigger(args []string) error {
+	flags := flag.NewFlagSet("trigger", flag.ExitOnError)
+	action := flags.String("action", "add", "Action to request: add, change or remove")
+	subsystem := flags.String("subsystem", "", "Comma separated subsystems to trigger")
+	dryRun := flags.Bool("dry-run", false, "Only print the devices that would be triggered")
+	wait := flags.Bool("settle", false, "Wait for udevd to process the events")
+	_ = flags.Parse(args)
+
+	switch *action {
+	case "add", "change", "remove":
+	default:
+		return fmt.Errorf("invalid action %q", *action)
+	}
+
+	var subsystems []string
+	if *subsystem != "" {
+		subsystems = strings.Split(*subsystem, ",")
+	}
+
+	err := filepath.WalkDir(filepath.Join(SYSFS_PATH, "devices"), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() || d.Name() != "uevent" {
+			return nil
+		}
+
+		dir := filepath.Dir(path)
+		if len(subsystems) > 0 {
+			target, err := os.Readlink(filepath.Join(dir, "subsystem"))
+			if err != nil || !slices.Contains(subsystems, filepath.Base(target)) {
+				return nil
+			}
+		}
+
+		if *dryRun {
+			fmt.Println(strings.TrimPrefix(dir, SYSFS_PATH))
+			return nil
+		}
+		if err := os.WriteFile(path, []byte(*action), 0); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", dir, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if *wait && !*dryRun {
+		return udev.Settle(DEFAULT_SETTLE_TIMEOUT)
+	}
+	return nil
+}
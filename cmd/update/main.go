@@ -0,0 +1,209 @@
+/*
+ * Copyright (c) 2025 Manjeet Singh <itsmanjeet1998@gmail.com>.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 3.
+ *
+ * This program is distributed in the hope that it will be useful, but
+ * WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+ * General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"syscall"
+
+	"chillos/pkg/boot"
+	"chillos/pkg/kernel/ioctl"
+)
+
+const (
+	BLKFLSBUF = 0x1261
+)
+
+var (
+	checksum string
+	reboot   bool
+	status   bool
+)
+
+func init() {
+	flag.StringVar(&checksum, "checksum", "", "Expected sha256 checksum of the system image")
+	flag.BoolVar(&reboot, "reboot", false, "Reboot into the updated slot")
+	flag.BoolVar(&status, "status", false, "Print current boot state")
+	flag.Usage = func() {
+		fmt.Printf("Usage: %s [OPTIONS] <system.img>\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+}
+
+func main() {
+	flag.Parse()
+
+	state, err := boot.LoadState(boot.StatePath)
+	if err != nil {
+		log.Fatalf("failed to load boot state: %v", err)
+	}
+
+	if status {
+		fmt.Printf("Active: %s\nPrevious: %s\nGood: %v\nTries: %d/%d\n",
+			state.Active, state.Previous, state.Good, state.Tries, state.MaxTries)
+		if running, err := boot.Booted(); err == nil {
+			fmt.Printf("Running: %s\n", running)
+		}
+		for _, slot := range []boot.Slot{boot.SlotA, boot.SlotB} {
+			fmt.Printf("Slot %s: %s %s\n", slot, state.Devices[slot], state.Checksums[slot])
+		}
+		return
+	}
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	// the running slot is mounted, only the other one is safe to write
+	running, err := boot.Booted()
+	if err != nil {
+		log.Fatalf("failed to find the running slot: %v", err)
+	}
+	slot := running.Other()
+
+	device, ok := state.Devices[slot]
+	if !ok || device == "" {
+		log.Fatalf("no device configured for slot %s", slot)
+	}
+	if device == state.Devices[running] {
+		log.Fatalf("slot %s uses %s of the running slot %s", slot, device, running)
+	}
+
+	// the initramfs only trusts root hashes from the kernel command line
+	hashes := rootHashFlags()
+	verified := hashes["roothash-"+string(slot)] || (slot == boot.SlotA && hashes["roothash"])
+	if len(hashes) != 0 && !verified {
+		log.Printf("warning: system is verified with dm-verity, slot %s only boots with -roothash-%s on the kernel command line", slot, slot)
+	}
+
+	sum, size, err := write(flag.Arg(0), device)
+	if err != nil {
+		log.Fatalf("failed to write %s to slot %s: %v", flag.Arg(0), slot, err)
+	}
+
+	if checksum != "" && !strings.EqualFold(checksum, sum) {
+		log.Fatalf("checksum mismatch for %s, expected %s got %s", flag.Arg(0), checksum, sum)
+	}
+
+	if err := verify(device, size, sum); err != nil {
+		log.Fatalf("failed to verify slot %s: %v", slot, err)
+	}
+
+	state.Switch(slot, sum)
+	if err := state.Save(boot.StatePath); err != nil {
+		log.Fatalf("failed to save boot state: %v", err)
+	}
+	fmt.Printf("slot %s updated (%s), will be used on next boot\n", slot, sum)
+
+	if reboot {
+		init, err := os.FindProcess(1)
+		if err != nil {
+			log.Fatalf("failed to find init process %v", err)
+		}
+		if err := init.Signal(syscall.SIGINT); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// rootHashFlags returns the names of the dm-verity root hash flags on
+// the kernel command line.
+func rootHashFlags() map[string]bool {
+	flags := map[string]bool{}
+	cmdline, err := os.ReadFile("/proc/cmdline")
+	if err != nil {
+		return flags
+	}
+	for _, arg := range strings.Fields(string(cmdline)) {
+		name, _, _ := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if strings.HasPrefix(name, "roothash") {
+			flags[name] = true
+		}
+	}
+	return flags
+}
+
+func write(image, device string) (string, int64, error) {
+	src, err := os.Open(image)
+	if err != nil {
+		return "", 0, err
+	}
+	defer src.Close()
+
+	info, err := src.Stat()
+	if err != nil {
+		return "", 0, err
+	}
+
+	dst, err := os.OpenFile(device, os.O_WRONLY, 0)
+	if err != nil {
+		return "", 0, err
+	}
+	defer dst.Close()
+
+	capacity, err := dst.Seek(0, io.SeekEnd)
+	if err != nil {
+		return "", 0, err
+	}
+	if capacity < info.Size() {
+		return "", 0, fmt.Errorf("image size %d exceeds slot size %d", info.Size(), capacity)
+	}
+	if _, err := dst.Seek(0, io.SeekStart); err != nil {
+		return "", 0, err
+	}
+
+	hash := sha256.New()
+	n, err := io.Copy(dst, io.TeeReader(src, hash))
+	if err != nil {
+		return "", 0, err
+	}
+
+	if err := dst.Sync(); err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), n, nil
+}
+
+func verify(device string, size int64, sum string) error {
+	file, err := os.Open(device)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// drop cached pages so we hash what actually landed on the disk
+	_ = ioctl.Call(file.Fd(), BLKFLSBUF, 0)
+
+	hash := sha256.New()
+	if _, err := io.CopyN(hash, file, size); err != nil {
+		return err
+	}
+
+	if got := hex.EncodeToString(hash.Sum(nil)); got != sum {
+		return fmt.Errorf("checksum mismatch, expected %s got %s", sum, got)
+	}
+	return nil
+}
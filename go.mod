@@ -2,6 +2,10 @@ module chillos
 
 go 1.24.2
 
-require golang.org/x/image v0.27.0
+require (
+	github.com/klauspost/compress v1.18.0
+	github.com/ulikunitz/xz v0.5.15
+	golang.org/x/image v0.27.0
+)
 
 require golang.org/x/text v0.27.0 // indirect
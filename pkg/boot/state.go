@@ -0,0 +1,192 @@
+/*
+ * Copyright (c) 2025 Manjeet Singh <itsmanjeet1998@gmail.com>.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 3.
+ *
+ * This program is distributed in the hope that it will be useful, but
+ * WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+ * General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package boot
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	DataPath  = "/data"
+	StatePath = DataPath + "/system/boot.json"
+
+	// the slot the running system was booted from, written by the
+	// initramfs to the tmpfs so it never outlives the boot
+	BootedPath = "/cache/temp/boot-slot"
+
+	DefaultMaxTries = 3
+)
+
+type Slot string
+
+const (
+	SlotA Slot = "a"
+	SlotB Slot = "b"
+)
+
+func (s Slot) Other() Slot {
+	if s == SlotA {
+		return SlotB
+	}
+	return SlotA
+}
+
+type State struct {
+	Active    Slot            `json:"active"`
+	Previous  Slot            `json:"previous,omitempty"`
+	Good      bool            `json:"good"`
+	Tries     int             `json:"tries"`
+	MaxTries  int             `json:"max-tries"`
+	Devices   map[Slot]string `json:"devices"`
+	Checksums map[Slot]string `json:"checksums,omitempty"`
+}
+
+func NewState() *State {
+	return &State{
+		Active:    SlotA,
+		Good:      true,
+		MaxTries:  DefaultMaxTries,
+		Devices:   map[Slot]string{},
+		Checksums: map[Slot]string{},
+	}
+}
+
+func LoadState(path string) (*State, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	s := NewState()
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("invalid boot state %s: %v", path, err)
+	}
+
+	if s.Active != SlotA && s.Active != SlotB {
+		return nil, fmt.Errorf("invalid active slot %q", s.Active)
+	}
+	if s.MaxTries <= 0 {
+		s.MaxTries = DefaultMaxTries
+	}
+	if s.Devices == nil {
+		s.Devices = map[Slot]string{}
+	}
+	if s.Checksums == nil {
+		s.Checksums = map[Slot]string{}
+	}
+	return s, nil
+}
+
+func (s *State) Save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	tmp := path + ".new"
+	file, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.Write(data); err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	if err := file.Sync(); err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// SaveBooted records slot as the one the running system was booted from.
+func SaveBooted(slot Slot) error {
+	return os.WriteFile(BootedPath, []byte(slot), 0644)
+}
+
+// Booted returns the slot the running system was booted from. Active may
+// already point to another slot waiting for a reboot, so it is no way to
+// tell which slot is in use.
+func Booted() (Slot, error) {
+	data, err := os.ReadFile(BootedPath)
+	if err != nil {
+		return "", err
+	}
+
+	slot := Slot(strings.TrimSpace(string(data)))
+	if slot != SlotA && slot != SlotB {
+		return "", fmt.Errorf("invalid booted slot %q", slot)
+	}
+	return slot, nil
+}
+
+// Pick selects the slot for the current boot and counts the attempt. A
+// slot that is not yet marked good gets MaxTries attempts before we fall
+// back to the previous slot.
+func (s *State) Pick() (Slot, bool) {
+	if s.Good {
+		return s.Active, false
+	}
+
+	s.Tries++
+	if s.Tries <= s.MaxTries || s.Previous == "" {
+		return s.Active, false
+	}
+
+	s.Fallback()
+	return s.Active, true
+}
+
+// Fallback gives up on the active slot and goes back to the other one.
+func (s *State) Fallback() {
+	s.Active, s.Previous = s.Active.Other(), s.Active
+	s.Good = true
+	s.Tries = 0
+}
+
+// MarkGood records that the active slot booted successfully.
+func (s *State) MarkGood() {
+	s.Good = true
+	s.Tries = 0
+}
+
+// Switch makes slot the active one for the next boot, keeping the
+// current slot around as the fallback.
+func (s *State) Switch(slot Slot, checksum string) {
+	if slot != s.Active {
+		s.Previous = s.Active
+	}
+	s.Active = slot
+	s.Good = false
+	s.Tries = 0
+	s.Checksums[slot] = checksum
+}
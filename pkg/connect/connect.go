@@ -20,8 +20,16 @@ package connect
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"net"
 	"reflect"
+	"syscall"
+	"time"
+)
+
+const (
+	// descriptors accepted with a single read
+	MaxFds = 16
 )
 
 type Connection struct {
@@ -48,6 +56,14 @@ func (c *Connection) Close() error {
 	return c.conn.Close()
 }
 
+func (c *Connection) SetDeadline(t time.Time) error {
+	return c.conn.SetDeadline(t)
+}
+
+func (c *Connection) SetWriteDeadline(t time.Time) error {
+	return c.conn.SetWriteDeadline(t)
+}
+
 func (c *Connection) Send(cmd string, payload, reply any) error {
 	encoder := json.NewEncoder(c.conn)
 
@@ -85,6 +101,75 @@ func (c *Connection) Receive() (string, []byte, error) {
 	return t.Type, t.Payload, nil
 }
 
+// Read and Write use the connection as a plain stream, for protocols
+// other than JSON transactions.
+func (c *Connection) Read(p []byte) (int, error) {
+	return c.conn.Read(p)
+}
+
+func (c *Connection) Write(p []byte) (int, error) {
+	return c.conn.Write(p)
+}
+
+// WriteFds writes p with fds attached, the peer receives duplicates of
+// them along with the first byte of p.
+func (c *Connection) WriteFds(p []byte, fds []int) (int, error) {
+	if len(fds) == 0 {
+		return c.conn.Write(p)
+	}
+
+	conn, ok := c.conn.(*net.UnixConn)
+	if !ok {
+		return 0, fmt.Errorf("descriptors can only be passed over unix sockets")
+	}
+	n, _, err := conn.WriteMsgUnix(p, syscall.UnixRights(fds...), nil)
+	if err == nil && n < len(p) {
+		var m int
+		m, err = conn.Write(p[n:])
+		n += m
+	}
+	return n, err
+}
+
+// ReadFds reads into p and returns the descriptors that came with it, the
+// caller owns them.
+func (c *Connection) ReadFds(p []byte) (int, []int, error) {
+	conn, ok := c.conn.(*net.UnixConn)
+	if !ok {
+		n, err := c.conn.Read(p)
+		return n, nil, err
+	}
+
+	oob := make([]byte, syscall.CmsgSpace(MaxFds*4))
+	n, oobn, _, _, err := conn.ReadMsgUnix(p, oob)
+	if err != nil {
+		return n, nil, err
+	}
+	if oobn == 0 {
+		if n == 0 && len(p) != 0 {
+			return 0, nil, io.EOF
+		}
+		return n, nil, nil
+	}
+
+	msgs, err := syscall.ParseSocketControlMessage(oob[:oobn])
+	if err != nil {
+		return n, nil, err
+	}
+	var fds []int
+	for _, msg := range msgs {
+		rights, err := syscall.ParseUnixRights(&msg)
+		if err != nil {
+			continue
+		}
+		for _, fd := range rights {
+			syscall.CloseOnExec(fd)
+		}
+		fds = append(fds, rights...)
+	}
+	return n, fds, nil
+}
+
 func (c *Connection) Fd() int {
 	v := reflect.Indirect(reflect.ValueOf(c.conn))
 	conn := v.FieldByName("conn")
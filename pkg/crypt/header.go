@@ -0,0 +1,217 @@
+/*
+ * Copyright (c) 2025 Manjeet Singh <itsmanjeet1998@gmail.com>.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 3.
+ *
+ * This program is distributed in the hope that it will be useful, but
+ * WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+ * General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package crypt implements the key-slot header used for encrypted
+// partitions.
+//
+// The first HeaderSize bytes of the partition hold a little endian header:
+//
+//	magic          [8]byte   "CHILLCRY"
+//	version        uint32    1
+//	payload offset uint32    start of encrypted data, in 512 byte sectors
+//	cipher         [32]byte  dm-crypt cipher spec, "aes-xts-plain64"
+//	key size       uint32    master key size in bytes
+//	reserved       uint32
+//	key slots      [8]slot
+//
+// Each slot wraps the master key with AES-256-GCM using a key derived from
+// the passphrase with PBKDF2-HMAC-SHA256:
+//
+//	active         uint32    1 if the slot is in use
+//	iterations     uint32    PBKDF2 iterations
+//	salt           [32]byte
+//	nonce          [12]byte
+//	reserved       [4]byte
+//	wrapped key    [96]byte  GCM sealed master key, tag included
+//
+// The payload starts at PayloadOffset and is mapped with the dm-crypt
+// target using the raw master key.
+package crypt
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	HeaderSize    = 4096
+	PayloadOffset = 2048 // 1MiB in sectors
+	SectorSize    = 512
+
+	Cipher            = "aes-xts-plain64"
+	KeySize           = 64
+	DefaultIterations = 200000
+
+	slotCount      = 8
+	wrappedKeySize = 96
+)
+
+var (
+	magic = [8]byte{'C', 'H', 'I', 'L', 'L', 'C', 'R', 'Y'}
+
+	ErrNotEncrypted  = errors.New("not an encrypted partition")
+	ErrWrongPassword = errors.New("no key slot matches the passphrase")
+)
+
+type Slot struct {
+	Active     uint32
+	Iterations uint32
+	Salt       [32]byte
+	Nonce      [12]byte
+	_          [4]byte
+	WrappedKey [wrappedKeySize]byte
+}
+
+type Header struct {
+	Magic         [8]byte
+	Version       uint32
+	PayloadOffset uint32
+	Cipher        [32]byte
+	KeySize       uint32
+	_             uint32
+	Slots         [slotCount]Slot
+}
+
+// NewHeader creates a header for a freshly generated master key, which is
+// returned alongside.
+func NewHeader() (*Header, []byte, error) {
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, nil, err
+	}
+
+	h := &Header{
+		Magic:         magic,
+		Version:       1,
+		PayloadOffset: PayloadOffset,
+		KeySize:       KeySize,
+	}
+	copy(h.Cipher[:], Cipher)
+	return h, key, nil
+}
+
+func ReadHeader(r io.ReaderAt) (*Header, error) {
+	buf := make([]byte, HeaderSize)
+	if _, err := r.ReadAt(buf, 0); err != nil {
+		return nil, fmt.Errorf("failed to read header: %v", err)
+	}
+
+	var h Header
+	if err := binary.Read(bytes.NewReader(buf), binary.LittleEndian, &h); err != nil {
+		return nil, err
+	}
+
+	if h.Magic != magic {
+		return nil, ErrNotEncrypted
+	}
+	if h.Version != 1 {
+		return nil, fmt.Errorf("unsupported header version %d", h.Version)
+	}
+	if h.KeySize == 0 || h.KeySize+16 > wrappedKeySize {
+		return nil, fmt.Errorf("invalid key size %d", h.KeySize)
+	}
+	return &h, nil
+}
+
+func (h *Header) Write(w io.WriterAt) error {
+	var buf bytes.Buffer
+	if err := binary.Write(&buf, binary.LittleEndian, h); err != nil {
+		return err
+	}
+	buf.Write(make([]byte, HeaderSize-buf.Len()))
+
+	_, err := w.WriteAt(buf.Bytes(), 0)
+	return err
+}
+
+func (h *Header) CipherSpec() string {
+	return string(bytes.TrimRight(h.Cipher[:], "\x00"))
+}
+
+// AddKey stores key in the first free slot, protected by passphrase.
+func (h *Header) AddKey(key []byte, passphrase string, iterations int) error {
+	if len(key) != int(h.KeySize) {
+		return fmt.Errorf("invalid key size %d", len(key))
+	}
+
+	for i := range h.Slots {
+		slot := &h.Slots[i]
+		if slot.Active != 0 {
+			continue
+		}
+
+		slot.Iterations = uint32(iterations)
+		if _, err := rand.Read(slot.Salt[:]); err != nil {
+			return err
+		}
+		if _, err := rand.Read(slot.Nonce[:]); err != nil {
+			return err
+		}
+
+		aead, err := slotCipher(slot, passphrase)
+		if err != nil {
+			return err
+		}
+		copy(slot.WrappedKey[:], aead.Seal(nil, slot.Nonce[:], key, h.Magic[:]))
+		slot.Active = 1
+		return nil
+	}
+	return fmt.Errorf("no free key slot")
+}
+
+// Unlock returns the master key from the first slot that opens with
+// passphrase.
+func (h *Header) Unlock(passphrase string) ([]byte, error) {
+	for i := range h.Slots {
+		slot := &h.Slots[i]
+		if slot.Active == 0 {
+			continue
+		}
+
+		aead, err := slotCipher(slot, passphrase)
+		if err != nil {
+			return nil, err
+		}
+
+		key, err := aead.Open(nil, slot.Nonce[:], slot.WrappedKey[:h.KeySize+uint32(aead.Overhead())], h.Magic[:])
+		if err == nil {
+			return key, nil
+		}
+	}
+	return nil, ErrWrongPassword
+}
+
+func slotCipher(slot *Slot, passphrase string) (cipher.AEAD, error) {
+	kek, err := pbkdf2.Key(sha256.New, passphrase, slot.Salt[:], int(slot.Iterations), 32)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,94 @@
+/*
+ * Copyright (c) 2025 Manjeet Singh <itsmanjeet1998@gmail.com>.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 3.
+ *
+ * This program is distributed in the hope that it will be useful, but
+ * WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+ * General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package crypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Encrypt writes src encrypted the same way the kernel's aes-xts-plain64
+// would into the payload area of dst. It allows building encrypted images
+// without device-mapper on the host.
+func Encrypt(dst io.WriterAt, src io.Reader, h *Header, key []byte) error {
+	if h.CipherSpec() != Cipher {
+		return fmt.Errorf("unsupported cipher %s", h.CipherSpec())
+	}
+	if len(key) != KeySize {
+		return fmt.Errorf("invalid key size %d", len(key))
+	}
+
+	data, err := aes.NewCipher(key[:KeySize/2])
+	if err != nil {
+		return err
+	}
+	tweak, err := aes.NewCipher(key[KeySize/2:])
+	if err != nil {
+		return err
+	}
+
+	offset := int64(h.PayloadOffset) * SectorSize
+	sector := make([]byte, SectorSize)
+	for n := uint64(0); ; n++ {
+		if _, err := io.ReadFull(src, sector); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			if err != io.ErrUnexpectedEOF {
+				return err
+			}
+		}
+
+		encryptSector(data, tweak, n, sector)
+		if _, err := dst.WriteAt(sector, offset+int64(n)*SectorSize); err != nil {
+			return err
+		}
+		clear(sector)
+	}
+}
+
+func encryptSector(data, tweak cipher.Block, n uint64, sector []byte) {
+	var t [aes.BlockSize]byte
+	binary.LittleEndian.PutUint64(t[:], n)
+	tweak.Encrypt(t[:], t[:])
+
+	for i := 0; i < len(sector); i += aes.BlockSize {
+		block := sector[i : i+aes.BlockSize]
+		for j := range block {
+			block[j] ^= t[j]
+		}
+		data.Encrypt(block, block)
+		for j := range block {
+			block[j] ^= t[j]
+		}
+
+		// multiply the tweak by x in GF(2^128)
+		var carry byte
+		for j := range t {
+			next := t[j] >> 7
+			t[j] = t[j]<<1 | carry
+			carry = next
+		}
+		if carry != 0 {
+			t[0] ^= 0x87
+		}
+	}
+}
@@ -0,0 +1,104 @@
+/*
+ * Copyright (c) 2025 Manjeet Singh <itsmanjeet1998@gmail.com>.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 3.
+ *
+ * This program is distributed in the hope that it will be useful, but
+ * WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+ * General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+// Package decompress reads the compressed files kernels ship, firmware and
+// modules, choosing the format by file extension.
+package decompress
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+var (
+	// Extensions in the order files are searched for
+	Extensions = []string{".zst", ".xz", ".gz"}
+)
+
+// IsCompressed reports whether path has a known compression extension.
+func IsCompressed(path string) bool {
+	switch filepath.Ext(path) {
+	case ".zst", ".xz", ".gz":
+		return true
+	}
+	return false
+}
+
+// NewReader decompresses r according to the extension of name, unknown
+// extensions are passed through as is.
+func NewReader(r io.Reader, name string) (io.ReadCloser, error) {
+	switch filepath.Ext(name) {
+	case ".zst":
+		decoder, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return decoder.IOReadCloser(), nil
+	case ".xz":
+		reader, err := xz.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return io.NopCloser(reader), nil
+	case ".gz":
+		return gzip.NewReader(r)
+	}
+	return io.NopCloser(r), nil
+}
+
+// ReadFile reads and decompresses the file at path.
+func ReadFile(path string) ([]byte, error) {
+	if !IsCompressed(path) {
+		return os.ReadFile(path)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader, err := NewReader(file, path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return data, nil
+}
+
+// Find returns the first of path and its compressed variants that exists.
+func Find(path string) (string, bool) {
+	if _, err := os.Stat(path); err == nil {
+		return path, true
+	}
+	for _, ext := range Extensions {
+		if _, err := os.Stat(path + ext); err == nil {
+			return path + ext, true
+		}
+	}
+	return "", false
+}
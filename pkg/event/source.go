@@ -21,3 +21,10 @@ type Source interface {
 	Fd() int
 	Read() (Event, error)
 }
+
+// Finisher is a source with a last event to deliver once it reached its
+// end.
+type Finisher interface {
+	Source
+	Finish() Event
+}
@@ -23,10 +23,12 @@ import (
 	"runtime/debug"
 	"time"
 
+	"chillos/pkg/event/frame"
 	"chillos/pkg/event/life"
 	"chillos/pkg/event/resize"
 	"chillos/pkg/graphics/argb"
 	"chillos/pkg/graphics/backend"
+	"chillos/pkg/graphics/region"
 	"chillos/pkg/graphics/style"
 	"chillos/pkg/graphics/widget"
 )
@@ -65,7 +67,7 @@ func Run(w widget.Widget) error {
 			}
 			l.SetBounds(canvas.Bounds())
 			l.Draw(canvas)
-			bk.Update()
+			bk.Update(region.Of(canvas.Bounds()))
 
 			time.Sleep(time.Second * time.Duration(recoveryDelay))
 			os.Exit(1)
@@ -83,14 +85,19 @@ func Run(w widget.Widget) error {
 		w.SetBounds(canvas.Bounds())
 
 		w.Draw(canvas)
-		bk.Update()
+		bk.Update(region.Of(canvas.Bounds()))
 	}
 
+	// drawing waits for the backend to present the last frame, polling
+	// blocks until then instead of drawing frames nobody sees
+	ready := false
 	for {
 		events, err := bk.PollEvents()
 		if err == nil {
 			for _, event := range events {
 				switch event.(type) {
+				case frame.Event:
+					ready = true
 				case resize.Event:
 					w.SetDirty(true)
 				case life.End:
@@ -104,12 +111,15 @@ func Run(w widget.Widget) error {
 		canvas := bk.Canvas()
 		w.SetBounds(canvas.Bounds())
 
-		if w.Dirty() {
+		if ready && w.Dirty() {
+			// damage is taken before drawing, which marks it painted
+			damage := widget.DamageOf(w).Intersect(canvas.Bounds())
 			w.Draw(canvas)
 			w.SetDirty(false)
-			bk.Update()
-		} else {
-			time.Sleep(16 * time.Millisecond)
+			if !damage.Empty() {
+				bk.Update(damage)
+				ready = false
+			}
 		}
 	}
 
@@ -20,6 +20,13 @@ package backend
 import (
 	"chillos/pkg/event"
 	"chillos/pkg/graphics/canvas"
+	"chillos/pkg/graphics/region"
+)
+
+const (
+	// the longest PollEvents waits when nothing happens, widgets changing
+	// with time get to redraw this often
+	IdleTimeout = 1000
 )
 
 type Backend interface {
@@ -29,5 +36,7 @@ type Backend interface {
 	PollEvents() ([]event.Event, error)
 	Listen(source event.Source) error
 	Canvas() canvas.Canvas
-	Update()
+	// Update shows the canvas, only damage has changed since the last one.
+	// A frame.Event follows once it is a good time to draw the next frame.
+	Update(damage region.Region)
 }
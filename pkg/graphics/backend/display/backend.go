@@ -24,16 +24,24 @@ import (
 
 	"chillos/pkg/connect"
 	"chillos/pkg/event"
+	"chillos/pkg/event/life"
+	"chillos/pkg/graphics/backend"
 	"chillos/pkg/graphics/canvas"
+	"chillos/pkg/graphics/region"
+	"chillos/pkg/kernel/memfd"
 	"chillos/pkg/kernel/poll"
-	"chillos/pkg/kernel/shm"
-	"chillos/service/display/surface"
+	"chillos/service/display/protocol"
 )
 
+// destroyed is the surface id once the display removed the surface
+const destroyed = -1
+
 type Backend struct {
-	poll *poll.Listener
-	conn *connect.Connection
-	img  *shm.Image
+	poll   *poll.Listener
+	source *Connection
+	conn   *protocol.Conn
+	id     int
+	img    *memfd.Image
 }
 
 func (b *Backend) Init() (err error) {
@@ -42,51 +50,88 @@ func (b *Backend) Init() (err error) {
 		return fmt.Errorf("failed to setup poll: %v", err)
 	}
 
-	b.conn, err = connect.Connect("display")
+	c, err := connect.Connect("display")
 	if err != nil {
 		return fmt.Errorf("failed to connect to display: %v", err)
 	}
+	b.conn = protocol.NewConn(c)
+	b.source = &Connection{Connection: c, conn: b.conn}
 
-	if err := b.poll.Add(&Connection{b.conn}); err != nil {
+	if err := b.poll.Add(b.source); err != nil {
+		_ = c.Close()
 		return fmt.Errorf("failed to add connection to display: %v", err)
 	}
 
-	ev := surface.Create{
+	reply, err := b.conn.Call(protocol.CreateSurface{
 		Rect: image.Rect(0, 0, 800, 600),
+	})
+	if err != nil {
+		_ = c.Close()
+		return err
 	}
 
-	var r surface.Created
-	if err := b.conn.Send("surface.Create", ev, &r); err != nil {
-		_ = b.conn.Close()
-		return err
+	r, ok := reply.(protocol.SurfaceCreated)
+	if !ok {
+		_ = c.Close()
+		return fmt.Errorf("unexpected reply %T to surface creation", reply)
 	}
 
-	b.img, err = r.Image()
+	b.id = r.Id
+	b.img, err = memfd.NewImageFromFd(r.Buffer, r.Rect.Dx(), r.Rect.Dy())
 	if err != nil {
-		_ = b.conn.Close()
+		_ = c.Close()
 		return err
 	}
 	return nil
 }
 
 func (b *Backend) Terminate() {
-	_ = b.conn.Close()
+	if b.id != destroyed {
+		if _, err := b.conn.Request(protocol.DestroySurface{Id: b.id}); err != nil {
+			log.Printf("failed to destroy surface: %v", err)
+		}
+	}
+	_ = b.img.Destroy()
+	_ = b.source.Close()
 }
 
 func (b *Backend) PollEvents() ([]event.Event, error) {
-	events, err := b.poll.Poll()
+	timeout := backend.IdleTimeout
+	if b.conn.Buffered() != 0 {
+		timeout = 0
+	}
+
+	events, err := b.poll.PollTimeout(timeout)
 	if err != nil {
 		return nil, err
 	}
-	for _, ev := range events {
+
+	// events that arrived while waiting for a reply
+	for b.conn.Buffered() != 0 {
+		if ev, err := b.source.Read(); err == nil && ev != nil {
+			events = append(events, ev)
+		}
+	}
+
+	for i, ev := range events {
 		switch ev := ev.(type) {
-		case surface.Resize:
+		case protocol.SurfaceDestroyed:
+			if ev.Id != b.id {
+				continue
+			}
+			// the display closed the window, the app has nothing to draw on
+			log.Printf("surface %d destroyed by display", ev.Id)
+			b.id = destroyed
+			events[i] = life.End{}
+		case protocol.Resize:
 			log.Printf("Got resize event %v", ev)
-			b.img, err = ev.Image()
+			img, err := memfd.NewImageFromFd(ev.Buffer, ev.Rect.Dx(), ev.Rect.Dy())
 			if err != nil {
 				log.Printf("failed to attach image: %v", err)
 				return nil, err
 			}
+			_ = b.img.Destroy()
+			b.id, b.img = ev.Id, img
 			log.Printf("resizing surface: %v", b.img.Bounds())
 		}
 	}
@@ -97,13 +142,20 @@ func (b *Backend) Canvas() canvas.Canvas {
 	return b.img
 }
 
-func (b *Backend) Update() {
-	d := surface.Damage{
-		Id:   b.img.Key(),
-		Rect: b.img.Bounds(),
+// Update asks for a frame callback and sends the damage, the display
+// answers with a frame.Event once it presented it.
+func (b *Backend) Update(damage region.Region) {
+	if _, err := b.conn.Request(protocol.Frame{Id: b.id}); err != nil {
+		log.Println("failed to request frame", err)
+	}
+
+	d := protocol.Damage{
+		Id:    b.id,
+		Rect:  damage.Bounds(),
+		Rects: damage,
 	}
 
-	if err := b.conn.Send("surface.Damage", d, nil); err != nil {
+	if _, err := b.conn.Request(d); err != nil {
 		log.Println("failed send damage call", err)
 	}
 }
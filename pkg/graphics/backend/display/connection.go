@@ -18,53 +18,48 @@
 package display
 
 import (
-	"encoding/json"
+	"errors"
+	"io"
 	"log"
 
 	"chillos/pkg/connect"
 	"chillos/pkg/event"
-	"chillos/pkg/event/button"
-	"chillos/pkg/event/cursor"
-	"chillos/pkg/event/key"
-	"chillos/service/display/surface"
+	"chillos/pkg/event/frame"
+	"chillos/pkg/event/life"
+	"chillos/service/display/protocol"
 )
 
 type Connection struct {
 	*connect.Connection
+	conn *protocol.Conn
+}
+
+// Finish ends the app when the display goes away.
+func (c *Connection) Finish() event.Event {
+	log.Printf("display connection closed")
+	return life.End{}
 }
 
 func (c *Connection) Read() (event.Event, error) {
-	cmd, buf, err := c.Receive()
+	msg, err := c.conn.Read()
+	if errors.Is(err, protocol.ErrBroken) {
+		// the stream cannot be trusted anymore
+		return nil, io.EOF
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	switch cmd {
-	case "key-event":
-		var k key.Event
-		if err := json.Unmarshal(buf, &k); err != nil {
-			return nil, err
-		}
-		return k, nil
-	case "button-event":
-		var k button.Event
-		if err := json.Unmarshal(buf, &k); err != nil {
-			return nil, err
-		}
-		return k, nil
-	case "cursor-event":
-		var k cursor.Event
-		if err := json.Unmarshal(buf, &k); err != nil {
-			return nil, err
-		}
-		return k, nil
-	case "surface.Resize":
-		var k surface.Resize
-		if err := json.Unmarshal(buf, &k); err != nil {
-			return nil, err
+	switch msg.Kind {
+	case protocol.Event:
+		if _, ok := msg.Body.(protocol.FrameDone); ok {
+			return frame.Event{}, nil
 		}
-		log.Printf("got resize event: %v", k)
-		return k, nil
+		return msg.Body, nil
+	case protocol.Error:
+		log.Printf("display: %v", msg.Body)
+	default:
+		log.Printf("unexpected %v opcode %d from display", msg.Kind, msg.Opcode)
 	}
 	return nil, nil
 }
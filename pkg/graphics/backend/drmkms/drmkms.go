@@ -18,37 +18,82 @@
 package drmkms
 
 import (
+	"errors"
 	"fmt"
 	"image"
 	"image/draw"
 	"log"
 	"path/filepath"
+	"strings"
 	"sync"
 	"syscall"
+	"time"
 
 	"chillos/pkg/event"
+	"chillos/pkg/event/frame"
+	"chillos/pkg/event/resize"
 	"chillos/pkg/graphics/argb"
+	"chillos/pkg/graphics/backend"
 	"chillos/pkg/graphics/canvas"
+	"chillos/pkg/graphics/region"
 	"chillos/pkg/kernel/drm"
 	"chillos/pkg/kernel/input"
 	"chillos/pkg/kernel/poll"
+	"chillos/pkg/udev"
 )
 
+// Framebuffer is one of the buffers drawn into and scanned out in turn.
+// Drawing only touches what changed, so a buffer coming back for drawing
+// first has to catch up on what it missed while the others were shown.
 type Framebuffer struct {
 	id      uint32
 	backend *drm.Framebuffer
 	buffer  []byte
+
+	// age counts the frames since the buffer was last shown, 0 when its
+	// content is undefined and has to be copied whole
+	age    int
+	damage region.Region
 }
 
+const (
+	// app.Run only draws once the flip to the last frame completed, so
+	// two buffers are enough and Update never finds a flip pending. Only
+	// callers that don't wait for the frame.Event block in Update.
+	bufferCount = 2
+
+	// a flip completes at the next vblank, one not reported by then is
+	// not going to be
+	flipTimeout = 100 * time.Millisecond
+)
+
 type Backend struct {
 	mutex     sync.Mutex
 	card      *drm.Card
 	listener  *poll.Listener
+	monitor   *udev.Monitor
+	inputs    map[string]*input.Device
 	buffers   []Framebuffer
+	front     int
 	next      int
 	crtc      *drm.Crtc
 	mode      drm.ModeInfo
 	connector *drm.Connector
+
+	// page flips are queued when the driver supports them, flips counts
+	// them so a completion is matched with the flip it belongs to
+	pageFlip bool
+	flipping bool
+	flips    uint64
+
+	// a hotplug waits for the pending flip instead of blocking on it
+	replug   bool
+	replugAt time.Time
+
+	// frame events not yet handed out by PollEvents
+	pending []event.Event
+	// damage drawn but not presented yet
+	unshown region.Region
 }
 
 func (d *Backend) Terminate() {
@@ -71,49 +116,235 @@ func (d *Backend) Init() (err error) {
 		return err
 	}
 
+	// completed page flips are read from the card
+	if err := d.listener.Add(poll.Card{Card: d.card}); err != nil {
+		log.Printf("no page flip support: %v", err)
+	} else {
+		d.pageFlip = true
+	}
+
 	if err := d.listenInputDevices(); err != nil {
 		_ = d.card.Close()
 		_ = d.listener.Close()
 		return err
 	}
 
+	// without udevd devices plugged in later are simply not picked up
+	if d.monitor, err = udev.NewMonitor("input", "drm"); err != nil {
+		log.Printf("no hotplug support: %v", err)
+	} else if err := d.Listen(d.monitor); err != nil {
+		log.Printf("no hotplug support: %v", err)
+		_ = d.monitor.Close()
+		d.monitor = nil
+	}
+
 	return nil
 }
 
 func (d *Backend) Canvas() canvas.Canvas {
+	return d.image(d.buffers[d.next])
+}
+
+func (d *Backend) image(fb Framebuffer) *argb.Image {
 	return argb.NewImageWithBuffer(
 		image.Rect(0, 0, int(d.mode.Hdisplay), int(d.mode.Vdisplay)),
-		d.buffers[d.next].buffer,
-		int(d.buffers[d.next].backend.Pitch),
+		fb.buffer,
+		int(fb.backend.Pitch),
 	)
 }
 
-func (d *Backend) Update() {
+// Update queues a page flip to the drawn buffer, shown at the next vblank
+// with the frame.Event following once it is. Drivers without page flips
+// get a mode set instead, which is shown at once but may tear.
+func (d *Backend) Update(damage region.Region) {
+	// the card refuses another flip while one is pending
+	d.waitFlip()
+	fb := d.buffers[d.next]
+
+	if d.pageFlip {
+		err := d.card.PageFlip(d.crtc.ID, fb.id, drm.PageFlipEvent, d.flips+1)
+		if err == nil {
+			d.flips++
+			d.flipping = true
+			d.present(damage)
+			return
+		}
+		if !errors.Is(err, syscall.EINVAL) && !errors.Is(err, syscall.EOPNOTSUPP) {
+			log.Printf("failed to flip: %v", err)
+			d.retry(damage)
+			return
+		}
+		log.Printf("no page flip support, falling back to mode sets: %v", err)
+		d.pageFlip = false
+	}
+
 	connectors := []uint32{d.connector.ID}
-	if err := d.card.SetCrtc(d.crtc.ID, d.buffers[d.next].id, 0, 0, &connectors[0], 1, &d.mode); err != nil {
-		log.Printf("failed to flip: %v", err)
-	} else {
-		d.prepareBackBuffer()
-		d.next ^= 1
+	if err := d.card.SetCrtc(d.crtc.ID, fb.id, 0, 0, &connectors[0], 1, &d.mode); err != nil {
+		log.Printf("failed to set CRTC: %v", err)
+		d.retry(damage)
+		return
+	}
+	d.present(damage)
+	d.prepareBackBuffer()
+
+	// the mode set is done once it returns
+	d.pending = append(d.pending, frame.Event{})
+}
+
+// retry lets drawing go on after a failed present, the buffer keeps what
+// was drawn and is presented with the next frame.
+func (d *Backend) retry(damage region.Region) {
+	d.unshown.Union(damage)
+	d.pending = append(d.pending, frame.Event{})
+}
+
+// present makes the next buffer the front one and adds damage to what the
+// others missed.
+func (d *Backend) present(damage region.Region) {
+	damage = append(damage, d.unshown...)
+	d.unshown = nil
+
+	for i := range d.buffers {
+		fb := &d.buffers[i]
+		if i == d.next {
+			fb.age, fb.damage = 1, nil
+			continue
+		}
+		if fb.age != 0 {
+			fb.age++
+		}
+		fb.damage.Union(damage)
+	}
+
+	d.front = d.next
+	d.next = (d.next + 1) % len(d.buffers)
+}
+
+// flipped handles a completed page flip, the buffer shown before is free
+// to be drawn into again.
+func (d *Backend) flipped(ev drm.VBlank) []event.Event {
+	if ev.Type != drm.EventFlipComplete || !d.flipping || ev.UserData != d.flips {
+		return nil
+	}
+	d.flipping = false
+	d.prepareBackBuffer()
+
+	events := []event.Event{frame.Event{}}
+	if d.replug {
+		d.replug = false
+		events = append(events, d.hotplug()...)
+	}
+	return events
+}
+
+// waitFlip blocks until the pending page flip completed, one the card
+// never reports is given up on. Either way the frame.Event it owes and a
+// hotplug that waited for it are queued, the back buffer is left as it is.
+func (d *Backend) waitFlip() {
+	if !d.flipping {
+		return
+	}
+	if err := d.readFlip(); err != nil {
+		log.Printf("gave up waiting for page flip: %v", err)
+	}
+	d.flipping = false
+	d.pending = append(d.pending, frame.Event{})
+
+	if d.replug {
+		d.replug = false
+		d.pending = append(d.pending, d.hotplug()...)
+	}
+}
+
+// readFlip reads events from the card until the pending flip completed.
+func (d *Backend) readFlip() error {
+	efd, err := syscall.EpollCreate1(syscall.EPOLL_CLOEXEC)
+	if err != nil {
+		return err
+	}
+	defer syscall.Close(efd)
+
+	fd := d.card.Fd()
+	if err := syscall.EpollCtl(efd, syscall.EPOLL_CTL_ADD, fd, &syscall.EpollEvent{Events: syscall.EPOLLIN, Fd: int32(fd)}); err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(flipTimeout)
+	events := make([]syscall.EpollEvent, 1)
+	for {
+		// the card is non-blocking, everything ready is read at once
+		for {
+			ev, err := d.card.ReadEvent()
+			if errors.Is(err, syscall.EAGAIN) {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			if ev.Type == drm.EventFlipComplete && ev.UserData == d.flips {
+				return nil
+			}
+		}
+
+		timeout := time.Until(deadline)
+		if timeout <= 0 {
+			return fmt.Errorf("no flip completion within %v", flipTimeout)
+		}
+		if _, err := syscall.EpollWait(efd, events, int(timeout.Milliseconds())+1); err != nil && !errors.Is(err, syscall.EINTR) {
+			return err
+		}
 	}
 }
 
 func (d *Backend) PollEvents() ([]event.Event, error) {
-	return d.listener.Poll()
+	timeout := backend.IdleTimeout
+	if len(d.pending) != 0 {
+		timeout = 0
+	}
+
+	result := d.pending
+	d.pending = nil
+	events, err := d.listener.PollTimeout(timeout)
+	if err != nil {
+		d.pending = append(result, d.pending...)
+		return nil, err
+	}
+
+	for _, ev := range events {
+		switch ev := ev.(type) {
+		case udev.Event:
+			result = append(result, d.handleDeviceEvent(ev)...)
+		case drm.VBlank:
+			result = append(result, d.flipped(ev)...)
+		default:
+			result = append(result, ev)
+		}
+	}
+
+	// the flip may have gone with the old connector
+	if d.replug && time.Since(d.replugAt) > flipTimeout {
+		log.Printf("hotplug: page flip never completed")
+		d.flipping, d.replug = false, false
+		result = append(result, frame.Event{})
+		result = append(result, d.hotplug()...)
+	}
+	return result, nil
 }
 
+// prepareBackBuffer brings the next buffer up to date with front by
+// copying only what changed since it was last shown.
 func (d *Backend) prepareBackBuffer() {
-	src := argb.NewImageWithBuffer(
-		image.Rect(0, 0, int(d.mode.Hdisplay), int(d.mode.Vdisplay)),
-		d.buffers[d.next].buffer,
-		int(d.buffers[d.next].backend.Pitch),
-	)
-	dst := argb.NewImageWithBuffer(
-		image.Rect(0, 0, int(d.mode.Hdisplay), int(d.mode.Vdisplay)),
-		d.buffers[d.next^1].buffer,
-		int(d.buffers[d.next^1].backend.Pitch),
-	)
-	draw.Draw(dst, dst.Bounds(), src, image.Point{}, draw.Src)
+	back := &d.buffers[d.next]
+	src, dst := d.image(d.buffers[d.front]), d.image(*back)
+
+	if back.age == 0 {
+		draw.Draw(dst, dst.Bounds(), src, image.Point{}, draw.Src)
+	} else {
+		for _, r := range back.damage.Intersect(dst.Bounds()) {
+			draw.Draw(dst, r, src, r.Min, draw.Src)
+		}
+	}
+	back.damage = nil
 }
 
 func (d *Backend) setupCard() error {
@@ -126,32 +357,64 @@ func (d *Backend) setupCard() error {
 		return fmt.Errorf("failed to get DRM resources: %v", err)
 	}
 
-	found := false
+	if d.connector, err = d.findConnector(resources); err != nil {
+		return err
+	}
+
+	d.crtc, err = d.card.GetCrtc(resources.Crtcs[0])
+	if err != nil {
+		return fmt.Errorf("failed to get CRTC: %v", err)
+	}
+
+	d.mode = d.connector.Modes[0]
+	if err := d.createBuffers(); err != nil {
+		return err
+	}
+
+	connectors := []uint32{d.connector.ID}
+
+	if err := d.card.SetCrtc(d.crtc.ID, d.buffers[0].id, 0, 0, &connectors[0], 1, &d.mode); err != nil {
+		return fmt.Errorf("failed to set CRTC: %v", err)
+	}
+	return nil
+}
+
+// findConnector prefers the connector already in use and falls back to
+// the first connected one.
+func (d *Backend) findConnector(resources *drm.Resources) (*drm.Connector, error) {
+	var found *drm.Connector
 	for _, connID := range resources.Connectors {
 		conn, err := d.card.GetConnector(connID)
 		if err != nil {
 			continue
 		}
-		if conn.Connection == drm.Connected && len(conn.Modes) > 0 {
-			d.connector = conn
-			found = true
-			break
+		if conn.Connection != drm.Connected || len(conn.Modes) == 0 {
+			continue
+		}
+		if d.connector != nil && conn.ID == d.connector.ID {
+			return conn, nil
+		}
+		if found == nil {
+			found = conn
 		}
 	}
 
-	if !found {
-		return fmt.Errorf("no connected connector with valid modes found")
+	if found == nil {
+		return nil, fmt.Errorf("no connected connector with valid modes found")
 	}
+	return found, nil
+}
 
-	d.crtc, err = d.card.GetCrtc(resources.Crtcs[0])
-	if err != nil {
-		return fmt.Errorf("failed to get CRTC: %v", err)
+func (d *Backend) createBuffers() error {
+	d.buffers = make([]Framebuffer, bufferCount)
+	d.front, d.next = 0, 1
+	// a flip still pending was to a buffer that is gone, the frame.Event
+	// it owes is not
+	if d.flipping {
+		d.flipping = false
+		d.pending = append(d.pending, frame.Event{})
 	}
-
-	d.mode = d.connector.Modes[0]
-
-	d.buffers = make([]Framebuffer, 2)
-	for i := 0; i < 2; i++ {
+	for i := range d.buffers {
 		dumb, err := d.card.CreateDumb(d.mode.Hdisplay, d.mode.Vdisplay, 32)
 		if err != nil {
 			return fmt.Errorf("failed to create dumb buffer: %v", err)
@@ -171,30 +434,128 @@ func (d *Backend) setupCard() error {
 		}
 		d.buffers[i] = Framebuffer{id: fbID, backend: dumb, buffer: buffer}
 	}
+	return nil
+}
+
+func (d *Backend) destroyBuffers() {
+	for _, fb := range d.buffers {
+		_ = syscall.Munmap(fb.buffer)
+		_ = d.card.RemoveFramebuffer(fb.id)
+		_ = d.card.DestroyDumb(fb.backend.Handle)
+	}
+	d.buffers = nil
+}
+
+// hotplug re-probes connectors after the kernel reported a change and
+// switches to whatever is connected now.
+func (d *Backend) hotplug() []event.Event {
+	resources, err := d.card.GetResources()
+	if err != nil {
+		log.Printf("hotplug: failed to get DRM resources: %v", err)
+		return nil
+	}
+
+	conn, err := d.findConnector(resources)
+	if err != nil {
+		log.Printf("hotplug: %v", err)
+		return nil
+	}
+
+	mode := conn.Modes[0]
+	resized := mode.Hdisplay != d.mode.Hdisplay || mode.Vdisplay != d.mode.Vdisplay
+	if conn.ID != d.connector.ID {
+		log.Printf("hotplug: switching to connector %d", conn.ID)
+	}
+	d.connector, d.mode = conn, mode
+
+	if resized {
+		d.destroyBuffers()
+		if err := d.createBuffers(); err != nil {
+			log.Printf("hotplug: %v", err)
+			return nil
+		}
+	}
 
 	connectors := []uint32{d.connector.ID}
+	if err := d.card.SetCrtc(d.crtc.ID, d.buffers[d.front].id, 0, 0, &connectors[0], 1, &d.mode); err != nil {
+		log.Printf("hotplug: failed to set CRTC: %v", err)
+		return nil
+	}
 
-	if err := d.card.SetCrtc(d.crtc.ID, d.buffers[0].id, 0, 0, &connectors[0], 1, &d.mode); err != nil {
-		return fmt.Errorf("failed to set CRTC: %v", err)
+	if !resized {
+		return nil
+	}
+	return []event.Event{resize.Event{Width: int(d.mode.Hdisplay), Height: int(d.mode.Vdisplay)}}
+}
+
+func (d *Backend) handleDeviceEvent(e udev.Event) []event.Event {
+	switch e.Subsystem {
+	case "input":
+		if !strings.HasPrefix(e.DeviceName, "input/event") {
+			return nil
+		}
+		switch e.Action {
+		case "add":
+			d.addInputDevice(e.DeviceNode)
+		case "remove":
+			d.removeInputDevice(e.DeviceNode)
+		}
+
+	case "drm":
+		if e.Action != "change" || e.Properties["HOTPLUG"] != "1" {
+			return nil
+		}
+		// neither the buffers nor the CRTC may change under a pending
+		// flip, the hotplug is handled once it completed
+		if d.flipping {
+			d.replug, d.replugAt = true, time.Now()
+			return nil
+		}
+		return d.hotplug()
 	}
 	return nil
 }
 
+func (d *Backend) addInputDevice(path string) {
+	if _, ok := d.inputs[path]; ok {
+		return
+	}
+
+	src, err := input.OpenDevice(path)
+	if err != nil {
+		log.Printf("failed to open %s: %v", path, err)
+		return
+	}
+	if err := d.Listen(src); err != nil {
+		_ = src.Close()
+		return
+	}
+	d.inputs[path] = src
+}
+
+func (d *Backend) removeInputDevice(path string) {
+	src, ok := d.inputs[path]
+	if !ok {
+		return
+	}
+
+	d.mutex.Lock()
+	_ = d.listener.Remove(src)
+	d.mutex.Unlock()
+
+	_ = src.Close()
+	delete(d.inputs, path)
+}
+
 func (d *Backend) listenInputDevices() error {
 	matches, err := filepath.Glob("/dev/input/event*")
 	if err != nil {
 		return err
 	}
 
+	d.inputs = map[string]*input.Device{}
 	for _, match := range matches {
-		src, err := input.OpenDevice(match)
-		if err != nil {
-			continue
-		}
-		if err := d.Listen(src); err != nil {
-			_ = src.Close()
-			continue
-		}
+		d.addInputDevice(match)
 	}
 	return nil
 }
@@ -28,3 +28,23 @@ type Canvas interface {
 	At(x, y int) color.Color
 	ColorModel() color.Model
 }
+
+// Clip returns cv restricted to rect, drawing outside of it is dropped.
+func Clip(cv Canvas, rect image.Rectangle) Canvas {
+	return &clipped{Canvas: cv, rect: rect.Intersect(cv.Bounds())}
+}
+
+type clipped struct {
+	Canvas
+	rect image.Rectangle
+}
+
+func (c *clipped) Bounds() image.Rectangle {
+	return c.rect
+}
+
+func (c *clipped) Set(x, y int, col color.Color) {
+	if image.Pt(x, y).In(c.rect) {
+		c.Canvas.Set(x, y, col)
+	}
+}
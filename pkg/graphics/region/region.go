@@ -0,0 +1,121 @@
+/*
+ * Copyright (c) 2025 Manjeet Singh <itsmanjeet1998@gmail.com>.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 3.
+ *
+ * This program is distributed in the hope that it will be useful, but
+ * WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+ * General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package region tracks the parts of a surface that need repainting.
+package region
+
+import (
+	"image"
+	"slices"
+)
+
+const (
+	// past this many rectangles a region becomes its bounds, repainting
+	// a little more beats tracking every glyph
+	MaxRects = 16
+)
+
+// Region is a set of rectangles. They may overlap, it stays small by
+// merging rectangles whose union wastes little rather than by being exact.
+type Region []image.Rectangle
+
+func Of(rects ...image.Rectangle) Region {
+	var r Region
+	for _, rect := range rects {
+		r.Add(rect)
+	}
+	return r
+}
+
+func area(r image.Rectangle) int {
+	return r.Dx() * r.Dy()
+}
+
+func (r *Region) Add(rect image.Rectangle) {
+	if rect.Empty() {
+		return
+	}
+
+	for i := 0; i < len(*r); i++ {
+		other := (*r)[i]
+		if rect.In(other) {
+			return
+		}
+
+		union := other.Union(rect)
+		if other.In(rect) || area(union) <= area(other)+area(rect) {
+			// the union may now swallow or touch others
+			*r = slices.Delete(*r, i, i+1)
+			r.Add(union)
+			return
+		}
+	}
+
+	*r = append(*r, rect)
+	if len(*r) > MaxRects {
+		*r = Region{r.Bounds()}
+	}
+}
+
+func (r *Region) Union(other Region) {
+	for _, rect := range other {
+		r.Add(rect)
+	}
+}
+
+func (r *Region) Clear() {
+	*r = nil
+}
+
+func (r Region) Empty() bool {
+	return len(r) == 0
+}
+
+func (r Region) Bounds() image.Rectangle {
+	var bounds image.Rectangle
+	for _, rect := range r {
+		bounds = bounds.Union(rect)
+	}
+	return bounds
+}
+
+// Intersect returns the parts of r inside rect.
+func (r Region) Intersect(rect image.Rectangle) Region {
+	var result Region
+	for _, o := range r {
+		result.Add(o.Intersect(rect))
+	}
+	return result
+}
+
+// Overlaps reports whether any part of r is inside rect.
+func (r Region) Overlaps(rect image.Rectangle) bool {
+	for _, o := range r {
+		if o.Overlaps(rect) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r Region) Translate(p image.Point) Region {
+	result := make(Region, len(r))
+	for i, rect := range r {
+		result[i] = rect.Add(p)
+	}
+	return result
+}
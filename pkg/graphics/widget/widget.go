@@ -23,6 +23,7 @@ import (
 
 	"chillos/pkg/event"
 	"chillos/pkg/graphics/canvas"
+	"chillos/pkg/graphics/region"
 	"chillos/pkg/graphics/style"
 )
 
@@ -43,12 +44,30 @@ type Widget interface {
 	OnStyleChange(style.Style)
 }
 
+// Damager is a widget that knows which parts of it changed, a dirty
+// widget without it is repainted whole.
+type Damager interface {
+	Damage() region.Region
+}
+
+// DamageOf returns the parts of w to repaint.
+func DamageOf(w Widget) region.Region {
+	if !w.Dirty() {
+		return nil
+	}
+	if d, ok := w.(Damager); ok {
+		return d.Damage()
+	}
+	return region.Of(w.Bounds())
+}
+
 type Base struct {
 	Layout   func(base Widget, children []Widget) bool
 	Children []Widget
 
-	rect  image.Rectangle
-	dirty bool
+	rect   image.Rectangle
+	dirty  bool
+	damage region.Region
 }
 
 func (b *Base) Construct() {
@@ -106,16 +125,37 @@ func (b *Base) Dirty() bool {
 			return true
 		}
 	}
-	return b.dirty
+	return b.dirty || !b.damage.Empty()
 }
 
+// SetDirty(true) marks all of b for repainting, SetDirty(false) marks it
+// as painted, children included.
 func (b *Base) SetDirty(d bool) {
 	b.dirty = d
-	if d {
-		for _, child := range b.Children {
-			child.SetDirty(d)
-		}
+	b.damage = nil
+	for _, child := range b.Children {
+		child.SetDirty(d)
+	}
+}
+
+// AddDamage marks parts of b for repainting without dirtying all of it.
+func (b *Base) AddDamage(rects ...image.Rectangle) {
+	for _, r := range rects {
+		b.damage.Add(r.Intersect(b.rect))
+	}
+}
+
+// Damage returns all of b when dirty, otherwise its damage and the damage
+// of its children.
+func (b *Base) Damage() region.Region {
+	if b.dirty {
+		return region.Of(b.rect)
+	}
+	damage := slices.Clone(b.damage)
+	for _, child := range b.Children {
+		damage.Union(DamageOf(child))
 	}
+	return damage
 }
 
 func (b *Base) OnStyleChange(s style.Style) {
@@ -0,0 +1,81 @@
+/*
+ * Copyright (c) 2025 Manjeet Singh <itsmanjeet1998@gmail.com>.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 3.
+ *
+ * This program is distributed in the hope that it will be useful, but
+ * WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+ * General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+package block
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	SysClassBlock = "/sys/class/block"
+)
+
+// Devices lists the device nodes of every block device and partition
+// known to the kernel.
+func Devices() ([]string, error) {
+	entries, err := os.ReadDir(SysClassBlock)
+	if err != nil {
+		return nil, err
+	}
+
+	var devices []string
+	for _, entry := range entries {
+		devices = append(devices, filepath.Join("/dev", entry.Name()))
+	}
+	return devices, nil
+}
+
+// Find returns the device node for specs like UUID=..., LABEL=... or
+// plain device paths.
+func Find(spec string) (string, error) {
+	key, value, ok := strings.Cut(spec, "=")
+	if !ok || strings.HasPrefix(spec, "/") {
+		if _, err := os.Stat(spec); err != nil {
+			return "", err
+		}
+		return spec, nil
+	}
+
+	match := func(info Info) bool {
+		switch key {
+		case "UUID":
+			return strings.EqualFold(info.UUID, value)
+		case "LABEL":
+			return info.Label == value
+		}
+		return false
+	}
+	if key != "UUID" && key != "LABEL" {
+		return "", fmt.Errorf("unsupported device spec %q", spec)
+	}
+
+	devices, err := Devices()
+	if err != nil {
+		return "", err
+	}
+
+	for _, device := range devices {
+		info, err := Probe(device)
+		if err == nil && match(info) {
+			return device, nil
+		}
+	}
+	return "", fmt.Errorf("no device found for %s", spec)
+}
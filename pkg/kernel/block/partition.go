@@ -0,0 +1,169 @@
+/*
+ * Copyright (c) 2025 Manjeet Singh <itsmanjeet1998@gmail.com>.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 3.
+ *
+ * This program is distributed in the hope that it will be useful, but
+ * WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+ * General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+package block
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf16"
+)
+
+const (
+	gptSignature = "EFI PART"
+	mbrSignature = 0xaa55
+	mbrTypeGPT   = 0xee
+)
+
+type Partition struct {
+	Number  int
+	Start   uint64 // in sectors
+	Sectors uint64
+	Type    string // GUID for gpt, hex byte for dos
+	UUID    string
+	Label   string
+}
+
+type Table struct {
+	Type       string // gpt or dos
+	UUID       string
+	Partitions []Partition
+}
+
+// ReadPartitionTable reads the partition table of the disk at path.
+func ReadPartitionTable(path string) (*Table, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ParsePartitionTable(file)
+}
+
+// ParsePartitionTable understands GPT, with 512 or 4096 byte sectors, and
+// the primary partitions of an MBR.
+func ParsePartitionTable(r io.ReaderAt) (*Table, error) {
+	mbr := read(r, 0, 512)
+	if mbr == nil || binary.LittleEndian.Uint16(mbr[510:]) != mbrSignature {
+		return nil, fmt.Errorf("no partition table")
+	}
+
+	for _, sectorSize := range []int64{512, 4096} {
+		if table, ok := parseGPT(r, sectorSize); ok {
+			return table, nil
+		}
+	}
+
+	if mbr[446+4] == mbrTypeGPT {
+		return nil, fmt.Errorf("invalid GPT header")
+	}
+	return parseMBR(mbr), nil
+}
+
+func parseGPT(r io.ReaderAt, sectorSize int64) (*Table, bool) {
+	header := read(r, sectorSize, 92)
+	if header == nil || string(header[:8]) != gptSignature {
+		return nil, false
+	}
+
+	entriesLBA := int64(binary.LittleEndian.Uint64(header[72:]))
+	count := int(binary.LittleEndian.Uint32(header[80:]))
+	entrySize := int(binary.LittleEndian.Uint32(header[84:]))
+	if entrySize < 128 || count > 1024 {
+		return nil, false
+	}
+
+	entries := read(r, entriesLBA*sectorSize, count*entrySize)
+	if entries == nil {
+		return nil, false
+	}
+
+	table := &Table{Type: "gpt", UUID: formatGUID(header[56:72])}
+	for i := 0; i < count; i++ {
+		entry := entries[i*entrySize : (i+1)*entrySize]
+		if bytes.Equal(entry[:16], make([]byte, 16)) {
+			continue
+		}
+
+		first := binary.LittleEndian.Uint64(entry[32:])
+		last := binary.LittleEndian.Uint64(entry[40:])
+		table.Partitions = append(table.Partitions, Partition{
+			Number:  i + 1,
+			Start:   first * uint64(sectorSize/512),
+			Sectors: (last - first + 1) * uint64(sectorSize/512),
+			Type:    formatGUID(entry[0:16]),
+			UUID:    formatGUID(entry[16:32]),
+			Label:   utf16String(entry[56:128]),
+		})
+	}
+	return table, true
+}
+
+func parseMBR(mbr []byte) *Table {
+	signature := binary.LittleEndian.Uint32(mbr[440:])
+	table := &Table{Type: "dos", UUID: fmt.Sprintf("%08x", signature)}
+
+	for i := 0; i < 4; i++ {
+		entry := mbr[446+i*16 : 446+(i+1)*16]
+		if entry[4] == 0 {
+			continue
+		}
+		table.Partitions = append(table.Partitions, Partition{
+			Number:  i + 1,
+			Start:   uint64(binary.LittleEndian.Uint32(entry[8:])),
+			Sectors: uint64(binary.LittleEndian.Uint32(entry[12:])),
+			Type:    fmt.Sprintf("0x%02x", entry[4]),
+			UUID:    fmt.Sprintf("%08x-%02x", signature, i+1),
+		})
+	}
+	return table
+}
+
+// Partition returns the partition with the given number, 1 based.
+func (t *Table) Partition(number int) (Partition, bool) {
+	for _, p := range t.Partitions {
+		if p.Number == number {
+			return p, true
+		}
+	}
+	return Partition{}, false
+}
+
+// formatGUID formats the mixed endian on-disk GUID layout.
+func formatGUID(b []byte) string {
+	return fmt.Sprintf("%08x-%04x-%04x-%x-%x",
+		binary.LittleEndian.Uint32(b[0:4]),
+		binary.LittleEndian.Uint16(b[4:6]),
+		binary.LittleEndian.Uint16(b[6:8]),
+		b[8:10], b[10:16])
+}
+
+func utf16String(b []byte) string {
+	units := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		u := binary.LittleEndian.Uint16(b[i:])
+		if u == 0 {
+			break
+		}
+		units = append(units, u)
+	}
+	return strings.TrimSpace(string(utf16.Decode(units)))
+}
@@ -0,0 +1,199 @@
+/*
+ * Copyright (c) 2025 Manjeet Singh <itsmanjeet1998@gmail.com>.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 3.
+ *
+ * This program is distributed in the hope that it will be useful, but
+ * WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+ * General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+package block
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+type Info struct {
+	Type  string
+	UUID  string
+	Label string
+}
+
+type prober func(r io.ReaderAt) (Info, bool)
+
+var (
+	probers = []prober{
+		probeExt,
+		probeBtrfs,
+		probeXfs,
+		probeSquashfs,
+		probeSwap,
+		probeCrypt,
+		probeVfat,
+	}
+)
+
+// Probe identifies the filesystem on the block device or image at path.
+func Probe(path string) (Info, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Info{}, err
+	}
+	defer file.Close()
+
+	return ProbeReader(file)
+}
+
+func ProbeReader(r io.ReaderAt) (Info, error) {
+	for _, probe := range probers {
+		if info, ok := probe(r); ok {
+			return info, nil
+		}
+	}
+	return Info{}, fmt.Errorf("unknown filesystem")
+}
+
+func read(r io.ReaderAt, offset int64, size int) []byte {
+	buf := make([]byte, size)
+	if _, err := r.ReadAt(buf, offset); err != nil {
+		return nil
+	}
+	return buf
+}
+
+func formatUUID(b []byte) string {
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+func cstring(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i != -1 {
+		b = b[:i]
+	}
+	return strings.TrimSpace(string(b))
+}
+
+func probeExt(r io.ReaderAt) (Info, bool) {
+	sb := read(r, 1024, 256)
+	if sb == nil || binary.LittleEndian.Uint16(sb[56:]) != 0xEF53 {
+		return Info{}, false
+	}
+
+	const (
+		COMPAT_HAS_JOURNAL = 0x4
+		INCOMPAT_EXT4      = 0x40 | 0x80 | 0x200 // extents, 64bit, flex_bg
+	)
+
+	info := Info{
+		Type:  "ext2",
+		UUID:  formatUUID(sb[104:120]),
+		Label: cstring(sb[120:136]),
+	}
+	switch {
+	case binary.LittleEndian.Uint32(sb[96:])&INCOMPAT_EXT4 != 0:
+		info.Type = "ext4"
+	case binary.LittleEndian.Uint32(sb[92:])&COMPAT_HAS_JOURNAL != 0:
+		info.Type = "ext3"
+	}
+	return info, true
+}
+
+func probeBtrfs(r io.ReaderAt) (Info, bool) {
+	sb := read(r, 0x10000, 0x22b)
+	if sb == nil || string(sb[0x40:0x48]) != "_BHRfS_M" {
+		return Info{}, false
+	}
+	return Info{
+		Type:  "btrfs",
+		UUID:  formatUUID(sb[0x20:0x30]),
+		Label: cstring(sb[0x12b:0x22b]),
+	}, true
+}
+
+func probeXfs(r io.ReaderAt) (Info, bool) {
+	sb := read(r, 0, 120)
+	if sb == nil || string(sb[0:4]) != "XFSB" {
+		return Info{}, false
+	}
+	return Info{
+		Type:  "xfs",
+		UUID:  formatUUID(sb[32:48]),
+		Label: cstring(sb[108:120]),
+	}, true
+}
+
+func probeSquashfs(r io.ReaderAt) (Info, bool) {
+	sb := read(r, 0, 4)
+	if sb == nil || string(sb) != "hsqs" {
+		return Info{}, false
+	}
+	return Info{Type: "squashfs"}, true
+}
+
+func probeSwap(r io.ReaderAt) (Info, bool) {
+	sig := read(r, 4096-10, 10)
+	if sig == nil || (string(sig) != "SWAPSPACE2" && string(sig) != "SWAP-SPACE") {
+		return Info{}, false
+	}
+
+	info := Info{Type: "swap"}
+	if header := read(r, 1024, 44); header != nil {
+		info.UUID = formatUUID(header[12:28])
+		info.Label = cstring(header[28:44])
+	}
+	return info, true
+}
+
+func probeCrypt(r io.ReaderAt) (Info, bool) {
+	header := read(r, 0, 208)
+	if header == nil {
+		return Info{}, false
+	}
+
+	switch {
+	case string(header[0:8]) == "CHILLCRY":
+		return Info{Type: "chillos_crypt"}, true
+	case string(header[0:6]) == "LUKS\xba\xbe":
+		return Info{Type: "crypto_LUKS", UUID: cstring(header[168:208])}, true
+	}
+	return Info{}, false
+}
+
+func probeVfat(r io.ReaderAt) (Info, bool) {
+	bs := read(r, 0, 512)
+	if bs == nil || bs[510] != 0x55 || bs[511] != 0xAA {
+		return Info{}, false
+	}
+
+	var id []byte
+	var label string
+	switch {
+	case string(bs[82:87]) == "FAT32":
+		id, label = bs[67:71], cstring(bs[71:82])
+	case string(bs[54:57]) == "FAT":
+		id, label = bs[39:43], cstring(bs[43:54])
+	default:
+		return Info{}, false
+	}
+
+	if label == "NO NAME" {
+		label = ""
+	}
+	serial := binary.LittleEndian.Uint32(id)
+	return Info{
+		Type:  "vfat",
+		UUID:  fmt.Sprintf("%04X-%04X", serial>>16, serial&0xffff),
+		Label: label,
+	}, true
+}
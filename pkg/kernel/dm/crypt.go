@@ -0,0 +1,40 @@
+/*
+ * Copyright (c) 2025 Manjeet Singh <itsmanjeet1998@gmail.com>.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 3.
+ *
+ * This program is distributed in the hope that it will be useful, but
+ * WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+ * General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package dm
+
+import (
+	"encoding/hex"
+	"fmt"
+)
+
+type Crypt struct {
+	Cipher  string
+	Key     []byte
+	Device  string
+	Offset  uint64 // in 512 byte sectors
+	Sectors uint64
+}
+
+func (c Crypt) Target() Target {
+	return Target{
+		Start:  0,
+		Length: c.Sectors,
+		Type:   "crypt",
+		Params: fmt.Sprintf("%s %s 0 %s %d", c.Cipher, hex.EncodeToString(c.Key), c.Device, c.Offset),
+	}
+}
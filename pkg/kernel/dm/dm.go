@@ -0,0 +1,258 @@
+/*
+ * Copyright (c) 2025 Manjeet Singh <itsmanjeet1998@gmail.com>.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 3.
+ *
+ * This program is distributed in the hope that it will be useful, but
+ * WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+ * General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package dm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+
+	"chillos/pkg/kernel/ioctl"
+)
+
+const (
+	ControlPath = "/dev/mapper/control"
+
+	NameLen     = 128
+	UUIDLen     = 129
+	TypeNameLen = 16
+
+	ReadOnlyFlag   = 1 << 0
+	SuspendFlag    = 1 << 1
+	BufferFullFlag = 1 << 8
+
+	versionMajor = 4
+	versionMinor = 0
+	versionPatch = 0
+
+	bufferSize = 16 * 1024
+)
+
+type sysIoctl struct {
+	version     [3]uint32
+	dataSize    uint32
+	dataStart   uint32
+	targetCount uint32
+	openCount   int32
+	flags       uint32
+	eventNr     uint32
+	padding     uint32
+	dev         uint64
+	name        [NameLen]byte
+	uuid        [UUIDLen]byte
+	data        [7]byte
+}
+
+type sysTargetSpec struct {
+	sectorStart uint64
+	length      uint64
+	status      int32
+	next        uint32
+	targetType  [TypeNameLen]byte
+}
+
+type Target struct {
+	Start  uint64 // in 512 byte sectors
+	Length uint64 // in 512 byte sectors
+	Type   string
+	Params string
+}
+
+type Device struct {
+	Name  string
+	Major uint32
+	Minor uint32
+}
+
+func (d Device) Path() string {
+	return fmt.Sprintf("/dev/dm-%d", d.Minor)
+}
+
+var (
+	IoctlVersion     = ioctl.IOWR(0xfd, 0, int(unsafe.Sizeof(sysIoctl{})))
+	IoctlRemoveAll   = ioctl.IOWR(0xfd, 1, int(unsafe.Sizeof(sysIoctl{})))
+	IoctlListDevices = ioctl.IOWR(0xfd, 2, int(unsafe.Sizeof(sysIoctl{})))
+	IoctlDevCreate   = ioctl.IOWR(0xfd, 3, int(unsafe.Sizeof(sysIoctl{})))
+	IoctlDevRemove   = ioctl.IOWR(0xfd, 4, int(unsafe.Sizeof(sysIoctl{})))
+	IoctlDevSuspend  = ioctl.IOWR(0xfd, 6, int(unsafe.Sizeof(sysIoctl{})))
+	IoctlDevStatus   = ioctl.IOWR(0xfd, 7, int(unsafe.Sizeof(sysIoctl{})))
+	IoctlTableLoad   = ioctl.IOWR(0xfd, 9, int(unsafe.Sizeof(sysIoctl{})))
+)
+
+type Control struct {
+	file *os.File
+}
+
+func Open() (*Control, error) {
+	file, err := os.OpenFile(ControlPath, os.O_RDWR|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open device-mapper control: %w", err)
+	}
+	return &Control{file: file}, nil
+}
+
+func (c *Control) Close() error {
+	return c.file.Close()
+}
+
+func (c *Control) call(cmd int, name, uuid string, flags uint32, count int, payload []byte) (*sysIoctl, []byte, error) {
+	header := int(unsafe.Sizeof(sysIoctl{}))
+	size := header + len(payload)
+	if size < bufferSize {
+		size = bufferSize
+	}
+
+	buffer := make([]byte, size)
+	io := (*sysIoctl)(unsafe.Pointer(&buffer[0]))
+	io.version = [3]uint32{versionMajor, versionMinor, versionPatch}
+	io.dataSize = uint32(size)
+	io.dataStart = uint32(header)
+	io.targetCount = uint32(count)
+	io.flags = flags
+	copy(io.name[:NameLen-1], name)
+	copy(io.uuid[:UUIDLen-1], uuid)
+	copy(buffer[header:], payload)
+
+	if err := ioctl.Call(c.file.Fd(), uintptr(cmd), unsafe.Pointer(&buffer[0])); err != nil {
+		return nil, nil, err
+	}
+
+	if io.flags&BufferFullFlag != 0 {
+		return nil, nil, fmt.Errorf("device-mapper reply truncated")
+	}
+	return io, buffer[io.dataStart:io.dataSize], nil
+}
+
+func (c *Control) Version() ([3]uint32, error) {
+	io, _, err := c.call(IoctlVersion, "", "", 0, 0, nil)
+	if err != nil {
+		return [3]uint32{}, err
+	}
+	return io.version, nil
+}
+
+// Create creates the mapped device name and activates it with the given
+// table.
+func (c *Control) Create(name, uuid string, flags uint32, targets []Target) (Device, error) {
+	if _, _, err := c.call(IoctlDevCreate, name, uuid, 0, 0, nil); err != nil {
+		return Device{}, fmt.Errorf("create %s: %w", name, err)
+	}
+
+	if err := c.Load(name, flags, targets); err != nil {
+		_ = c.Remove(name)
+		return Device{}, err
+	}
+
+	dev, err := c.Resume(name)
+	if err != nil {
+		_ = c.Remove(name)
+		return Device{}, err
+	}
+	return dev, nil
+}
+
+func (c *Control) Load(name string, flags uint32, targets []Target) error {
+	var payload []byte
+	specSize := int(unsafe.Sizeof(sysTargetSpec{}))
+
+	for _, target := range targets {
+		// params are NUL terminated and every spec starts 8 byte aligned
+		length := (specSize + len(target.Params) + 1 + 7) &^ 7
+		entry := make([]byte, length)
+
+		spec := (*sysTargetSpec)(unsafe.Pointer(&entry[0]))
+		spec.sectorStart = target.Start
+		spec.length = target.Length
+		spec.next = uint32(length)
+		copy(spec.targetType[:TypeNameLen-1], target.Type)
+		copy(entry[specSize:], target.Params)
+
+		payload = append(payload, entry...)
+	}
+
+	if _, _, err := c.call(IoctlTableLoad, name, "", flags, len(targets), payload); err != nil {
+		return fmt.Errorf("load table %s: %w", name, err)
+	}
+	return nil
+}
+
+// Resume swaps in the loaded table and makes the device available.
+func (c *Control) Resume(name string) (Device, error) {
+	io, _, err := c.call(IoctlDevSuspend, name, "", 0, 0, nil)
+	if err != nil {
+		return Device{}, fmt.Errorf("resume %s: %w", name, err)
+	}
+	return deviceOf(name, io.dev), nil
+}
+
+func (c *Control) Remove(name string) error {
+	if _, _, err := c.call(IoctlDevRemove, name, "", 0, 0, nil); err != nil {
+		return fmt.Errorf("remove %s: %w", name, err)
+	}
+	return nil
+}
+
+func (c *Control) List() ([]Device, error) {
+	_, data, err := c.call(IoctlListDevices, "", "", 0, 0, nil)
+	if err != nil {
+		return nil, fmt.Errorf("list: %w", err)
+	}
+
+	// struct dm_name_list { __u64 dev; __u32 next; char name[]; }
+	var devices []Device
+	for offset := 0; offset+12 <= len(data); {
+		dev := binary.NativeEndian.Uint64(data[offset:])
+		next := binary.NativeEndian.Uint32(data[offset+8:])
+		if dev == 0 && next == 0 && len(devices) == 0 {
+			break
+		}
+
+		name := data[offset+12:]
+		if end := bytes.IndexByte(name, 0); end != -1 {
+			name = name[:end]
+		}
+		devices = append(devices, deviceOf(string(name), dev))
+
+		if next == 0 {
+			break
+		}
+		offset += int(next)
+	}
+	return devices, nil
+}
+
+func (c *Control) Status(name string) (Device, error) {
+	io, _, err := c.call(IoctlDevStatus, name, "", 0, 0, nil)
+	if err != nil {
+		return Device{}, fmt.Errorf("status %s: %w", name, err)
+	}
+	return deviceOf(name, io.dev), nil
+}
+
+// deviceOf decodes the dev_t returned by device-mapper, which uses the
+// kernel's huge_encode_dev format.
+func deviceOf(name string, dev uint64) Device {
+	return Device{
+		Name:  name,
+		Major: uint32((dev & 0xfff00) >> 8),
+		Minor: uint32((dev & 0xff) | ((dev >> 12) & 0xfff00)),
+	}
+}
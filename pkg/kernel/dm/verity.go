@@ -0,0 +1,223 @@
+/*
+ * Copyright (c) 2025 Manjeet Singh <itsmanjeet1998@gmail.com>.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 3.
+ *
+ * This program is distributed in the hope that it will be useful, but
+ * WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+ * General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package dm
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+)
+
+// The on-disk layout matches `veritysetup format`: a 512 byte superblock
+// padded to a full hash block, followed by the hash tree with the level
+// closest to the root first.
+
+const (
+	VerityBlockSize     = 4096
+	VerityAlgorithm     = "sha256"
+	VeritySuperblockLen = 512
+)
+
+var (
+	veritySignature = [8]byte{'v', 'e', 'r', 'i', 't', 'y', 0, 0}
+)
+
+type VeritySuperblock struct {
+	Signature     [8]byte
+	Version       uint32
+	HashType      uint32
+	UUID          [16]byte
+	Algorithm     [32]byte
+	DataBlockSize uint32
+	HashBlockSize uint32
+	DataBlocks    uint64
+	SaltSize      uint16
+	_             [6]byte
+	Salt          [256]byte
+	_             [168]byte
+}
+
+type Verity struct {
+	DataDevice string
+	HashDevice string
+	DataBlocks uint64
+	HashStart  uint64 // in hash blocks, relative to the start of HashDevice
+	RootHash   []byte
+	Salt       []byte
+}
+
+func (v Verity) Target() Target {
+	salt := "-"
+	if len(v.Salt) > 0 {
+		salt = hex.EncodeToString(v.Salt)
+	}
+	return Target{
+		Start:  0,
+		Length: v.DataBlocks * (VerityBlockSize / 512),
+		Type:   "verity",
+		Params: fmt.Sprintf("1 %s %s %d %d %d %d %s %s %s",
+			v.DataDevice, v.HashDevice,
+			VerityBlockSize, VerityBlockSize,
+			v.DataBlocks, v.HashStart,
+			VerityAlgorithm, hex.EncodeToString(v.RootHash), salt),
+	}
+}
+
+// ReadVeritySuperblock reads the superblock stored at offset of the hash
+// device.
+func ReadVeritySuperblock(r io.ReaderAt, offset int64) (*VeritySuperblock, error) {
+	buf := make([]byte, VeritySuperblockLen)
+	if _, err := r.ReadAt(buf, offset); err != nil {
+		return nil, fmt.Errorf("failed to read verity superblock: %w", err)
+	}
+
+	var sb VeritySuperblock
+	if err := binary.Read(bytes.NewReader(buf), binary.LittleEndian, &sb); err != nil {
+		return nil, err
+	}
+
+	if sb.Signature != veritySignature {
+		return nil, fmt.Errorf("invalid verity superblock signature")
+	}
+	if sb.Version != 1 || sb.HashType != 1 {
+		return nil, fmt.Errorf("unsupported verity version %d type %d", sb.Version, sb.HashType)
+	}
+	if sb.DataBlockSize != VerityBlockSize || sb.HashBlockSize != VerityBlockSize {
+		return nil, fmt.Errorf("unsupported verity block size %d/%d", sb.DataBlockSize, sb.HashBlockSize)
+	}
+	if algo := string(bytes.TrimRight(sb.Algorithm[:], "\x00")); algo != VerityAlgorithm {
+		return nil, fmt.Errorf("unsupported verity algorithm %s", algo)
+	}
+	if int(sb.SaltSize) > len(sb.Salt) {
+		return nil, fmt.Errorf("invalid verity salt size %d", sb.SaltSize)
+	}
+	return &sb, nil
+}
+
+// VerityFromSuperblock describes the verity target for a hash tree whose
+// superblock lives at offset bytes into hashDevice.
+func VerityFromSuperblock(sb *VeritySuperblock, dataDevice, hashDevice string, offset int64, rootHash []byte) Verity {
+	return Verity{
+		DataDevice: dataDevice,
+		HashDevice: hashDevice,
+		DataBlocks: sb.DataBlocks,
+		HashStart:  uint64(offset/VerityBlockSize) + 1,
+		RootHash:   rootHash,
+		Salt:       append([]byte(nil), sb.Salt[:sb.SaltSize]...),
+	}
+}
+
+// FormatVerity hashes dataBlocks blocks of r and writes the superblock and
+// hash tree to w starting at offset, returning the root hash.
+func FormatVerity(r io.ReaderAt, w io.WriterAt, dataBlocks uint64, offset int64, salt []byte) ([]byte, error) {
+	if dataBlocks == 0 {
+		return nil, fmt.Errorf("no data blocks to hash")
+	}
+	if offset%VerityBlockSize != 0 {
+		return nil, fmt.Errorf("hash offset %d is not block aligned", offset)
+	}
+	if len(salt) > 256 {
+		return nil, fmt.Errorf("salt too long")
+	}
+
+	sb := VeritySuperblock{
+		Signature:     veritySignature,
+		Version:       1,
+		HashType:      1,
+		DataBlockSize: VerityBlockSize,
+		HashBlockSize: VerityBlockSize,
+		DataBlocks:    dataBlocks,
+		SaltSize:      uint16(len(salt)),
+	}
+	copy(sb.Algorithm[:], VerityAlgorithm)
+	copy(sb.Salt[:], salt)
+
+	block := make([]byte, VerityBlockSize)
+	var digests [][]byte
+	for i := uint64(0); i < dataBlocks; i++ {
+		if _, err := r.ReadAt(block, int64(i)*VerityBlockSize); err != nil && err != io.EOF {
+			return nil, fmt.Errorf("failed to read block %d: %w", i, err)
+		}
+		digests = append(digests, verityHash(salt, block))
+		clear(block)
+	}
+
+	// build every level bottom up, they are written top down afterwards
+	var levels [][]byte
+	for range verityLevels(dataBlocks) {
+		level := packDigests(digests)
+		levels = append(levels, level)
+
+		digests = digests[:0]
+		for i := 0; i < len(level); i += VerityBlockSize {
+			digests = append(digests, verityHash(salt, level[i:i+VerityBlockSize]))
+		}
+	}
+	if len(digests) != 1 {
+		return nil, fmt.Errorf("invalid hash tree, %d root digests", len(digests))
+	}
+
+	var header bytes.Buffer
+	if err := binary.Write(&header, binary.LittleEndian, &sb); err != nil {
+		return nil, err
+	}
+	header.Write(make([]byte, VerityBlockSize-header.Len()))
+	if _, err := w.WriteAt(header.Bytes(), offset); err != nil {
+		return nil, err
+	}
+
+	position := offset + VerityBlockSize
+	for i := len(levels) - 1; i >= 0; i-- {
+		if _, err := w.WriteAt(levels[i], position); err != nil {
+			return nil, err
+		}
+		position += int64(len(levels[i]))
+	}
+
+	return digests[0], nil
+}
+
+func verityHash(salt, block []byte) []byte {
+	h := sha256.New()
+	h.Write(salt)
+	h.Write(block)
+	return h.Sum(nil)
+}
+
+func verityLevels(dataBlocks uint64) int {
+	const bits = 7 // log2(VerityBlockSize / sha256.Size)
+	levels := 0
+	for bits*levels < 64 && (dataBlocks-1)>>(bits*levels) != 0 {
+		levels++
+	}
+	return levels
+}
+
+func packDigests(digests [][]byte) []byte {
+	perBlock := VerityBlockSize / sha256.Size
+	count := (len(digests) + perBlock - 1) / perBlock
+
+	level := make([]byte, count*VerityBlockSize)
+	for i, digest := range digests {
+		copy(level[i*sha256.Size:], digest)
+	}
+	return level
+}
@@ -0,0 +1,118 @@
+/*
+ * Copyright (c) 2025 Manjeet Singh <itsmanjeet1998@gmail.com>.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 3.
+ *
+ * This program is distributed in the hope that it will be useful, but
+ * WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+ * General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package drm
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"chillos/pkg/kernel/ioctl"
+)
+
+const (
+	// page flip flags
+	PageFlipEvent = 0x01
+	PageFlipAsync = 0x02
+
+	// event types read from the card
+	EventVBlank       = 0x01
+	EventFlipComplete = 0x02
+	EventCrtcSequence = 0x03
+
+	eventHeaderSize = 8
+	vblankSize      = 32
+)
+
+type sysPageFlip struct {
+	crtcID   uint32
+	fbID     uint32
+	flags    uint32
+	reserved uint32
+	userData uint64
+}
+
+var (
+	IoctlPageFlip = ioctl.IOWR('d', 0xB0, int(unsafe.Sizeof(sysPageFlip{})))
+)
+
+// VBlank is drm_event_vblank, read from the card for vblanks asked for and
+// for page flips queued with PageFlipEvent once they completed.
+type VBlank struct {
+	Type     uint32
+	UserData uint64
+	Sec      uint32
+	Usec     uint32
+	Sequence uint32
+	CrtcID   uint32
+}
+
+func (e VBlank) Event() {}
+
+// Time is when the vblank happened on the monotonic clock.
+func (e VBlank) Time() time.Duration {
+	return time.Duration(e.Sec)*time.Second + time.Duration(e.Usec)*time.Microsecond
+}
+
+// PageFlip shows bufferid on crtcid at the next vblank without a mode
+// set. With PageFlipEvent a VBlank of type EventFlipComplete carrying
+// userData is read from the card once it happened, until then the old
+// buffer is still scanned out and another flip fails with EBUSY.
+func (c *Card) PageFlip(crtcid, bufferid, flags uint32, userData uint64) error {
+	flip := &sysPageFlip{
+		crtcID:   crtcid,
+		fbID:     bufferid,
+		flags:    flags,
+		userData: userData,
+	}
+	return ioctl.Call(uintptr(c.fd), uintptr(IoctlPageFlip), uintptr(unsafe.Pointer(flip)))
+}
+
+// ReadEvent reads the next event of the card. It reads one event at a
+// time, the ones left keep the card readable.
+func (c *Card) ReadEvent() (VBlank, error) {
+	var buf [vblankSize]byte
+	n, err := syscall.Read(c.fd, buf[:])
+	if err != nil {
+		return VBlank{}, err
+	}
+	if n == 0 {
+		return VBlank{}, io.EOF
+	}
+	if n < eventHeaderSize {
+		return VBlank{}, fmt.Errorf("short drm event of %d bytes", n)
+	}
+
+	typ := binary.NativeEndian.Uint32(buf[0:])
+	length := binary.NativeEndian.Uint32(buf[4:])
+	if (typ != EventVBlank && typ != EventFlipComplete) || length < vblankSize || n < vblankSize {
+		return VBlank{}, fmt.Errorf("unsupported drm event %d of %d bytes", typ, length)
+	}
+
+	return VBlank{
+		Type:     typ,
+		UserData: binary.NativeEndian.Uint64(buf[8:]),
+		Sec:      binary.NativeEndian.Uint32(buf[16:]),
+		Usec:     binary.NativeEndian.Uint32(buf[20:]),
+		Sequence: binary.NativeEndian.Uint32(buf[24:]),
+		CrtcID:   binary.NativeEndian.Uint32(buf[28:]),
+	}, nil
+}
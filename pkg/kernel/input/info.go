@@ -0,0 +1,101 @@
+/*
+ * Copyright (c) 2025 Manjeet Singh <itsmanjeet1998@gmail.com>.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 3.
+ *
+ * This program is distributed in the hope that it will be useful, but
+ * WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+ * General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+package input
+
+import (
+	"bytes"
+	"unsafe"
+
+	"chillos/pkg/kernel/ioctl"
+)
+
+const (
+	BusPCI       = 0x01
+	BusUSB       = 0x03
+	BusHIL       = 0x04
+	BusBluetooth = 0x05
+	BusVirtual   = 0x06
+	BusI8042     = 0x11
+	BusHost      = 0x19
+
+	stringSize = 256
+)
+
+// ID is struct input_id
+type ID struct {
+	BusType uint16
+	Vendor  uint16
+	Product uint16
+	Version uint16
+}
+
+var (
+	IoctlGetID   = ioctl.IOR('E', 0x02, int(unsafe.Sizeof(ID{})))
+	IoctlGetName = ioctl.IOR('E', 0x06, stringSize)
+	IoctlGetPhys = ioctl.IOR('E', 0x07, stringSize)
+	IoctlGetUniq = ioctl.IOR('E', 0x08, stringSize)
+)
+
+func (d *Device) ID() (ID, error) {
+	var id ID
+	err := ioctl.Call(uintptr(d.fd), uintptr(IoctlGetID), unsafe.Pointer(&id))
+	return id, err
+}
+
+// Name is the human readable device name, e.g. "AT Translated Set 2 keyboard".
+func (d *Device) Name() (string, error) {
+	return d.getString(IoctlGetName)
+}
+
+// Phys is the physical path of the device, e.g. "isa0060/serio0/input0".
+func (d *Device) Phys() (string, error) {
+	return d.getString(IoctlGetPhys)
+}
+
+// Uniq is the unique identifier, usually a serial number, often empty.
+func (d *Device) Uniq() (string, error) {
+	return d.getString(IoctlGetUniq)
+}
+
+func (d *Device) getString(cmd int) (string, error) {
+	buf := make([]byte, stringSize)
+	if err := ioctl.Call(uintptr(d.fd), uintptr(cmd), unsafe.Pointer(&buf[0])); err != nil {
+		return "", err
+	}
+	if i := bytes.IndexByte(buf, 0); i != -1 {
+		buf = buf[:i]
+	}
+	return string(buf), nil
+}
+
+func (id ID) Bus() string {
+	switch id.BusType {
+	case BusPCI:
+		return "pci"
+	case BusUSB:
+		return "usb"
+	case BusBluetooth:
+		return "bluetooth"
+	case BusVirtual:
+		return "virtual"
+	case BusI8042:
+		return "i8042"
+	case BusHost:
+		return "host"
+	}
+	return "input"
+}
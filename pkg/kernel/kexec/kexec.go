@@ -0,0 +1,84 @@
+/*
+ * Copyright (c) 2025 Manjeet Singh <itsmanjeet1998@gmail.com>.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 3.
+ *
+ * This program is distributed in the hope that it will be useful, but
+ * WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+ * General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+package kexec
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	KEXEC_FILE_UNLOAD       = 0x1
+	KEXEC_FILE_ON_CRASH     = 0x2
+	KEXEC_FILE_NO_INITRAMFS = 0x4
+)
+
+// Load stages kernel and initrd for the next LINUX_REBOOT_CMD_KEXEC.
+// An empty initrd boots the kernel without an initramfs.
+func Load(kernel, initrd, cmdline string) error {
+	kernelFile, err := os.Open(kernel)
+	if err != nil {
+		return err
+	}
+	defer kernelFile.Close()
+
+	flags := 0
+	initrdFd := -1
+	if initrd != "" {
+		initrdFile, err := os.Open(initrd)
+		if err != nil {
+			return err
+		}
+		defer initrdFile.Close()
+		initrdFd = int(initrdFile.Fd())
+	} else {
+		flags |= KEXEC_FILE_NO_INITRAMFS
+	}
+
+	// the length passed to the kernel includes the trailing NUL
+	cmdlinePtr, err := syscall.BytePtrFromString(cmdline)
+	if err != nil {
+		return err
+	}
+
+	if _, _, errno := syscall.Syscall6(SYS_KEXEC_FILE_LOAD,
+		kernelFile.Fd(), uintptr(initrdFd),
+		uintptr(len(cmdline)+1), uintptr(unsafe.Pointer(cmdlinePtr)),
+		uintptr(flags), 0); errno != 0 {
+		return fmt.Errorf("kexec_file_load %s: %v", kernel, errno)
+	}
+	return nil
+}
+
+// Unload drops the previously staged kernel.
+func Unload() error {
+	if _, _, errno := syscall.Syscall6(SYS_KEXEC_FILE_LOAD, 0, 0, 0, 0, KEXEC_FILE_UNLOAD, 0); errno != 0 {
+		return fmt.Errorf("kexec_file_load unload: %v", errno)
+	}
+	return nil
+}
+
+// Loaded reports whether a kernel is staged for kexec.
+func Loaded() bool {
+	data, err := os.ReadFile("/sys/kernel/kexec_loaded")
+	if err != nil {
+		return false
+	}
+	return len(data) > 0 && data[0] == '1'
+}
@@ -0,0 +1,56 @@
+/*
+ * Copyright (c) 2025 Manjeet Singh <itsmanjeet1998@gmail.com>.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 3.
+ *
+ * This program is distributed in the hope that it will be useful, but
+ * WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+ * General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package loop
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"chillos/pkg/kernel/ioctl"
+)
+
+const (
+	LOOP_CLR_FD = 0x4C01
+)
+
+// Attached lists the loop devices which currently have a backing file.
+func Attached() ([]string, error) {
+	matches, err := filepath.Glob("/sys/block/loop*/loop/backing_file")
+	if err != nil {
+		return nil, err
+	}
+
+	var devices []string
+	for _, match := range matches {
+		name := filepath.Base(filepath.Dir(filepath.Dir(match)))
+		devices = append(devices, filepath.Join("/dev", strings.TrimSpace(name)))
+	}
+	return devices, nil
+}
+
+func Detach(device string) error {
+	file, err := os.OpenFile(device, os.O_RDONLY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return ioctl.Call(file.Fd(), LOOP_CLR_FD, 0)
+}
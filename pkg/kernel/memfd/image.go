@@ -0,0 +1,126 @@
+/*
+ * Copyright (c) 2025 Manjeet Singh <itsmanjeet1998@gmail.com>.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 3.
+ *
+ * This program is distributed in the hope that it will be useful, but
+ * WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+ * General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package memfd
+
+import (
+	"fmt"
+	"image"
+	"syscall"
+
+	"chillos/pkg/graphics/argb"
+)
+
+const (
+	// a buffer with these seals can not be resized under a mapping
+	bufferSeals = F_SEAL_SHRINK | F_SEAL_GROW | F_SEAL_SEAL
+)
+
+// Image is an ARGB image in a sealed memory file, only processes given
+// its descriptor can map it.
+type Image struct {
+	argb.Image
+	fd int
+}
+
+// NewImage allocates an image the size of width and height.
+func NewImage(name string, width, height int) (*Image, error) {
+	// TODO: get bpp from format
+	size := width * height * 4
+	if size <= 0 {
+		return nil, fmt.Errorf("invalid image size %dx%d", width, height)
+	}
+
+	fd, err := Create(name, MFD_CLOEXEC|MFD_ALLOW_SEALING)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := syscall.Ftruncate(fd, int64(size)); err != nil {
+		_ = syscall.Close(fd)
+		return nil, err
+	}
+
+	if err := AddSeals(fd, bufferSeals); err != nil {
+		_ = syscall.Close(fd)
+		return nil, err
+	}
+
+	return mapImage(fd, width, height)
+}
+
+// NewImageFromFd maps an image received from another process, which has
+// to be sealed against shrinking so it can not fault under us. The image
+// owns fd, it is closed when mapping fails.
+func NewImageFromFd(fd int, width, height int) (*Image, error) {
+	if err := checkBuffer(fd, width*height*4); err != nil {
+		_ = syscall.Close(fd)
+		return nil, err
+	}
+	return mapImage(fd, width, height)
+}
+
+func checkBuffer(fd int, size int) error {
+	seals, err := Seals(fd)
+	if err != nil {
+		return err
+	}
+	if seals&F_SEAL_SHRINK == 0 {
+		return fmt.Errorf("buffer is not sealed against shrinking")
+	}
+
+	var stat syscall.Stat_t
+	if err := syscall.Fstat(fd, &stat); err != nil {
+		return err
+	}
+	if size <= 0 || stat.Size < int64(size) {
+		return fmt.Errorf("buffer of %d bytes too small for %d", stat.Size, size)
+	}
+	return nil
+}
+
+func mapImage(fd int, width, height int) (*Image, error) {
+	buf, err := syscall.Mmap(fd, 0, width*height*4, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+	if err != nil {
+		_ = syscall.Close(fd)
+		return nil, fmt.Errorf("mmap: %w", err)
+	}
+
+	return &Image{
+		Image: *argb.NewImageWithBuffer(image.Rect(0, 0, width, height), buf, width*4),
+		fd:    fd,
+	}, nil
+}
+
+// Fd returns the descriptor to share the image with.
+func (i *Image) Fd() int {
+	return i.fd
+}
+
+func (i *Image) Size() int {
+	return len(i.Buffer())
+}
+
+// Destroy unmaps the image and closes its descriptor, the memory is
+// freed once every process sharing it did the same.
+func (i *Image) Destroy() error {
+	err := syscall.Munmap(i.Buffer())
+	if cerr := syscall.Close(i.fd); err == nil {
+		err = cerr
+	}
+	return err
+}
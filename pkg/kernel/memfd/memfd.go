@@ -0,0 +1,66 @@
+/*
+ * Copyright (c) 2025 Manjeet Singh <itsmanjeet1998@gmail.com>.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 3.
+ *
+ * This program is distributed in the hope that it will be useful, but
+ * WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+ * General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package memfd creates anonymous memory files that can be sealed and
+// shared with other processes by passing their descriptor.
+package memfd
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	MFD_CLOEXEC       = 0x1
+	MFD_ALLOW_SEALING = 0x2
+
+	F_ADD_SEALS = 1033
+	F_GET_SEALS = 1034
+
+	F_SEAL_SEAL   = 0x1
+	F_SEAL_SHRINK = 0x2
+	F_SEAL_GROW   = 0x4
+	F_SEAL_WRITE  = 0x8
+)
+
+func Create(name string, flags int) (int, error) {
+	p, err := syscall.BytePtrFromString(name)
+	if err != nil {
+		return -1, err
+	}
+	fd, _, errno := syscall.Syscall(SYS_MEMFD_CREATE, uintptr(unsafe.Pointer(p)), uintptr(flags), 0)
+	if errno != 0 {
+		return -1, fmt.Errorf("memfd_create %s: %w", name, errno)
+	}
+	return int(fd), nil
+}
+
+func AddSeals(fd int, seals int) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_FCNTL, uintptr(fd), F_ADD_SEALS, uintptr(seals)); errno != 0 {
+		return fmt.Errorf("add seals: %w", errno)
+	}
+	return nil
+}
+
+func Seals(fd int) (int, error) {
+	seals, _, errno := syscall.Syscall(syscall.SYS_FCNTL, uintptr(fd), F_GET_SEALS, 0)
+	if errno != 0 {
+		return 0, fmt.Errorf("get seals: %w", errno)
+	}
+	return int(seals), nil
+}
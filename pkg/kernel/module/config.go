@@ -0,0 +1,184 @@
+package module
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+const (
+	ConfigPath  = "/config/modules"
+	CmdlinePath = "/proc/cmdline"
+)
+
+// Config describes /config/modules, the modprobe.d of the system.
+//
+//	{"options": {"snd_hda_intel": "power_save=1"},
+//	 "blacklist": ["pcspkr"],
+//	 "aliases": {"sound-slot-0": "snd_hda_intel"},
+//	 "load": ["loop"]}
+//
+// Blacklisted modules are not loaded for device aliases but can still be
+// loaded by name. Aliases may use wildcards and take precedence over the
+// ones modules declare. Modules in load are loaded at boot.
+type Config struct {
+	Options   map[string]string `json:"options"`
+	Blacklist []string          `json:"blacklist"`
+	Aliases   map[string]string `json:"aliases"`
+	Load      []string          `json:"load"`
+}
+
+// LoadConfig reads the configuration at path, a missing file is an empty
+// configuration.
+func LoadConfig(path string) (*Config, error) {
+	config := &Config{}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return config, nil
+		}
+		return config, err
+	}
+
+	if err := json.Unmarshal(data, config); err != nil {
+		return &Config{}, fmt.Errorf("invalid modules config %s: %v", path, err)
+	}
+
+	options := map[string]string{}
+	for name, value := range config.Options {
+		options[Normalize(name)] = value
+	}
+	config.Options = options
+	for i, name := range config.Blacklist {
+		config.Blacklist[i] = Normalize(name)
+	}
+	for alias, name := range config.Aliases {
+		config.Aliases[alias] = Normalize(name)
+	}
+	return config, nil
+}
+
+// ReadCmdline adds modprobe.blacklist= and module.param= from the kernel
+// command line, parameters given there come after the configured ones.
+func (c *Config) ReadCmdline(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	c.ParseCmdline(string(data))
+	return nil
+}
+
+func (c *Config) ParseCmdline(cmdline string) {
+	for _, arg := range splitCmdline(cmdline) {
+		key, value, hasValue := strings.Cut(arg, "=")
+
+		if key == "modprobe.blacklist" {
+			for _, name := range strings.Split(value, ",") {
+				if name != "" {
+					c.Blacklist = append(c.Blacklist, Normalize(name))
+				}
+			}
+			continue
+		}
+
+		name, param, ok := strings.Cut(key, ".")
+		if !ok || name == "" || param == "" || strings.Contains(name, "/") {
+			continue
+		}
+
+		if hasValue {
+			param += "=" + quoteParam(value)
+		}
+		if c.Options == nil {
+			c.Options = map[string]string{}
+		}
+		name = Normalize(name)
+		c.Options[name] = strings.TrimSpace(c.Options[name] + " " + param)
+	}
+}
+
+// ModuleOptions returns the parameters name is loaded with by default.
+func (c *Config) ModuleOptions(name string) string {
+	if c == nil {
+		return ""
+	}
+	return c.Options[Normalize(name)]
+}
+
+func (c *Config) IsBlacklisted(name string) bool {
+	if c == nil {
+		return false
+	}
+	return slices.Contains(c.Blacklist, Normalize(name))
+}
+
+// Resolve returns the modules the configured aliases map modalias to.
+func (c *Config) Resolve(modalias string) []string {
+	if c == nil {
+		return nil
+	}
+
+	var modules []string
+	for pattern, name := range c.Aliases {
+		if ok, _ := filepath.Match(pattern, modalias); ok && !slices.Contains(modules, name) {
+			modules = append(modules, name)
+		}
+	}
+	slices.Sort(modules)
+	return modules
+}
+
+// joinOptions joins parameter strings, later ones override earlier ones
+// as the kernel applies them in order.
+func joinOptions(options ...string) string {
+	var parts []string
+	for _, o := range options {
+		if o = strings.TrimSpace(o); o != "" {
+			parts = append(parts, o)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// splitCmdline splits the kernel command line like the kernel does,
+// double quotes keep spaces in values.
+func splitCmdline(cmdline string) []string {
+	var (
+		args    []string
+		current strings.Builder
+		quoted  bool
+		started bool
+	)
+	for _, r := range cmdline {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			started = true
+		case !quoted && (r == ' ' || r == '\t' || r == '\n'):
+			if started {
+				args = append(args, current.String())
+				current.Reset()
+				started = false
+			}
+		default:
+			current.WriteRune(r)
+			started = true
+		}
+	}
+	if started {
+		args = append(args, current.String())
+	}
+	return args
+}
+
+func quoteParam(value string) string {
+	if strings.ContainsAny(value, " \t") {
+		return `"` + value + `"`
+	}
+	return value
+}
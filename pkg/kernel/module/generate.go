@@ -0,0 +1,157 @@
+package module
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"chillos/pkg/decompress"
+)
+
+// IsModuleFile reports whether path looks like a kernel module, plain or
+// compressed.
+func IsModuleFile(path string) bool {
+	if strings.HasSuffix(path, ".ko") {
+		return true
+	}
+	for _, ext := range decompress.Extensions {
+		if strings.HasSuffix(path, ".ko"+ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// GenerateIndex scans every module under path and writes modules.dep,
+// modules.alias and modules.softdep the way depmod does. modules.builtin
+// comes with the kernel build and is left alone.
+func GenerateIndex(path string) error {
+	modules := map[string]Info{}
+	if err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !IsModuleFile(p) {
+			return err
+		}
+
+		// one broken module must not cost the index of all the others
+		info, err := Parse(p)
+		if err != nil {
+			log.Printf("skipping %s: %v", p, err)
+			return nil
+		}
+		info.Path, _ = filepath.Rel(path, p)
+		if info.Name == "" {
+			info.Name = NameOf(p)
+		}
+		modules[Normalize(info.Name)] = info
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(modules))
+	for name := range modules {
+		names = append(names, name)
+	}
+	slices.SortFunc(names, func(a, b string) int {
+		return strings.Compare(modules[a].Path, modules[b].Path)
+	})
+
+	if err := writeIndexFile(filepath.Join(path, DepFile), func(w *bufio.Writer) error {
+		for _, name := range names {
+			deps, err := loadOrder(modules, name, map[string]bool{}, nil)
+			if err != nil {
+				return err
+			}
+			// drop the module itself and list them the way modprobe
+			// reads them, last one first
+			deps = slices.DeleteFunc(deps, func(dep string) bool { return dep == name })
+			slices.Reverse(deps)
+
+			fmt.Fprintf(w, "%s:", modules[name].Path)
+			for _, dep := range deps {
+				if info, ok := modules[dep]; ok {
+					fmt.Fprintf(w, " %s", info.Path)
+				}
+			}
+			fmt.Fprintln(w)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if err := writeIndexFile(filepath.Join(path, AliasFile), func(w *bufio.Writer) error {
+		fmt.Fprintln(w, "# Aliases extracted from modules themselves.")
+		for _, name := range names {
+			for _, a := range modules[name].Aliases {
+				fmt.Fprintf(w, "alias %s %s\n", a, name)
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	return writeIndexFile(filepath.Join(path, SoftDepFile), func(w *bufio.Writer) error {
+		fmt.Fprintln(w, "# Soft dependencies extracted from modules themselves.")
+		for _, name := range names {
+			if softdep := modules[name].SoftDep; softdep != "" {
+				fmt.Fprintf(w, "softdep %s %s\n", name, softdep)
+			}
+		}
+		return nil
+	})
+}
+
+// loadOrder appends the dependencies of name, depth first, followed by
+// name itself.
+func loadOrder(modules map[string]Info, name string, visiting map[string]bool, order []string) ([]string, error) {
+	if slices.Contains(order, name) {
+		return order, nil
+	}
+	if visiting[name] {
+		return nil, fmt.Errorf("dependency cycle through %s", name)
+	}
+	visiting[name] = true
+
+	// like depmod, a missing dependency is left for the kernel to report
+	info, ok := modules[name]
+	if !ok {
+		return order, nil
+	}
+	for _, dep := range info.Depends {
+		var err error
+		if order, err = loadOrder(modules, Normalize(dep), visiting, order); err != nil {
+			return nil, err
+		}
+	}
+
+	delete(visiting, name)
+	return append(order, name), nil
+}
+
+func writeIndexFile(path string, write func(w *bufio.Writer) error) error {
+	file, err := os.Create(path + ".tmp")
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(file)
+	if err := write(w); err != nil {
+		file.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
@@ -0,0 +1,293 @@
+package module
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+const (
+	DepFile            = "modules.dep"
+	AliasFile          = "modules.alias"
+	SoftDepFile        = "modules.softdep"
+	BuiltinFile        = "modules.builtin"
+	BuiltinModinfoFile = "modules.builtin.modinfo"
+)
+
+var (
+	ErrNotFound = errors.New("module not found")
+)
+
+type SoftDep struct {
+	Pre  []string
+	Post []string
+}
+
+type alias struct {
+	pattern string
+	module  string
+}
+
+// Index answers module lookups from the files depmod would generate, all
+// names are normalized with underscores.
+type Index struct {
+	path     string
+	paths    map[string]string   // module name to path relative to path
+	deps     map[string][]string // module name to dependency paths, load order
+	exact    map[string][]string // alias without wildcards to modules
+	patterns map[string][]alias  // wildcard aliases keyed by their bus prefix
+	softdeps map[string]SoftDep
+	builtin  map[string]bool
+	config   *Config
+}
+
+// Normalize makes module names comparable, the kernel treats - and _ the
+// same.
+func Normalize(name string) string {
+	return strings.ReplaceAll(name, "-", "_")
+}
+
+// NameOf returns the module name of a, possibly compressed, module file.
+func NameOf(path string) string {
+	base := filepath.Base(path)
+	if i := strings.Index(base, ".ko"); i != -1 {
+		base = base[:i]
+	}
+	return Normalize(base)
+}
+
+// LoadIndex reads the index in path, usually /lib/modules/<release>.
+// Only modules.dep is required.
+func LoadIndex(path string) (*Index, error) {
+	idx := &Index{
+		path:     path,
+		paths:    map[string]string{},
+		deps:     map[string][]string{},
+		exact:    map[string][]string{},
+		patterns: map[string][]alias{},
+		softdeps: map[string]SoftDep{},
+		builtin:  map[string]bool{},
+	}
+
+	if err := readLines(filepath.Join(path, DepFile), idx.parseDep); err != nil {
+		return nil, err
+	}
+
+	for file, parse := range map[string]func(string) error{
+		AliasFile:   idx.parseAlias,
+		SoftDepFile: idx.parseSoftDep,
+		BuiltinFile: idx.parseBuiltin,
+	} {
+		if err := readLines(filepath.Join(path, file), parse); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+
+	if err := idx.readBuiltinModinfo(filepath.Join(path, BuiltinModinfoFile)); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return idx, nil
+}
+
+func readLines(path string, parse func(string) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if err := parse(line); err != nil {
+			return fmt.Errorf("%s:%d: %v", path, n, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// parseDep reads "path: dep dep", dependencies are listed the way modprobe
+// expects them, the last one has to be loaded first.
+func (idx *Index) parseDep(line string) error {
+	path, deps, ok := strings.Cut(line, ":")
+	if !ok {
+		return fmt.Errorf("invalid dependency line")
+	}
+
+	name := NameOf(path)
+	idx.paths[name] = path
+
+	fields := strings.Fields(deps)
+	slices.Reverse(fields)
+	idx.deps[name] = fields
+	return nil
+}
+
+func (idx *Index) parseAlias(line string) error {
+	fields := strings.Fields(line)
+	if len(fields) != 3 || fields[0] != "alias" {
+		return fmt.Errorf("invalid alias line")
+	}
+	idx.addAlias(fields[1], Normalize(fields[2]))
+	return nil
+}
+
+func (idx *Index) addAlias(pattern, module string) {
+	if !strings.ContainsAny(pattern, "*?[") {
+		idx.exact[pattern] = append(idx.exact[pattern], module)
+		return
+	}
+	prefix := aliasPrefix(pattern)
+	idx.patterns[prefix] = append(idx.patterns[prefix], alias{pattern: pattern, module: module})
+}
+
+// aliasPrefix is the bus part of an alias, e.g. pci for pci:v00008086d*,
+// or empty when the wildcard comes first.
+func aliasPrefix(s string) string {
+	end := strings.IndexAny(s, ":*?[")
+	if end == -1 || s[end] != ':' {
+		return ""
+	}
+	return s[:end]
+}
+
+// parseSoftDep reads "softdep module pre: a b post: c".
+func (idx *Index) parseSoftDep(line string) error {
+	fields := strings.Fields(line)
+	if len(fields) < 2 || fields[0] != "softdep" {
+		return fmt.Errorf("invalid softdep line")
+	}
+
+	name := Normalize(fields[1])
+	idx.softdeps[name] = ParseSoftDep(strings.Join(fields[2:], " "))
+	return nil
+}
+
+// ParseSoftDep parses the "pre: a b post: c" format used by both the
+// softdep modinfo tag and modules.softdep.
+func ParseSoftDep(s string) SoftDep {
+	var (
+		dep     SoftDep
+		current *[]string
+	)
+	for _, field := range strings.Fields(s) {
+		switch field {
+		case "pre:":
+			current = &dep.Pre
+		case "post:":
+			current = &dep.Post
+		default:
+			if current != nil {
+				*current = append(*current, Normalize(field))
+			}
+		}
+	}
+	return dep
+}
+
+func (idx *Index) parseBuiltin(line string) error {
+	idx.builtin[NameOf(line)] = true
+	return nil
+}
+
+// readBuiltinModinfo picks up the aliases of built-in modules so that
+// devices they drive are recognised as handled.
+func (idx *Index) readBuiltinModinfo(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range bytes.Split(data, []byte{0}) {
+		key, value, ok := strings.Cut(string(entry), "=")
+		if !ok {
+			continue
+		}
+		name, field, ok := strings.Cut(key, ".")
+		if !ok {
+			continue
+		}
+		name = Normalize(name)
+		idx.builtin[name] = true
+		if field == "alias" {
+			idx.addAlias(value, name)
+		}
+	}
+	return nil
+}
+
+// Path returns the absolute path of the module file.
+func (idx *Index) Path(name string) (string, bool) {
+	path, ok := idx.paths[Normalize(name)]
+	if !ok {
+		return "", false
+	}
+	return filepath.Join(idx.path, path), true
+}
+
+func (idx *Index) IsBuiltin(name string) bool {
+	return idx.builtin[Normalize(name)]
+}
+
+func (idx *Index) SoftDep(name string) SoftDep {
+	return idx.softdeps[Normalize(name)]
+}
+
+// Dependencies returns the absolute paths of everything name needs, in
+// the order they have to be loaded.
+func (idx *Index) Dependencies(name string) []string {
+	deps := idx.deps[Normalize(name)]
+	paths := make([]string, len(deps))
+	for i, dep := range deps {
+		paths[i] = filepath.Join(idx.path, dep)
+	}
+	return paths
+}
+
+// SetConfig applies the options and aliases of config to modules loaded
+// through the index.
+func (idx *Index) SetConfig(config *Config) {
+	idx.config = config
+}
+
+func (idx *Index) Config() *Config {
+	return idx.config
+}
+
+// Resolve returns the modules matching a modalias, configured aliases
+// replace the ones modules declare and exact aliases come first.
+func (idx *Index) Resolve(modalias string) []string {
+	if modules := idx.config.Resolve(modalias); len(modules) != 0 {
+		return modules
+	}
+
+	modules := slices.Clone(idx.exact[modalias])
+
+	for _, prefix := range []string{aliasPrefix(modalias), ""} {
+		for _, a := range idx.patterns[prefix] {
+			if ok, _ := filepath.Match(a.pattern, modalias); ok && !slices.Contains(modules, a.module) {
+				modules = append(modules, a.module)
+			}
+		}
+		if prefix == "" {
+			break
+		}
+	}
+
+	// a module name is its own alias
+	if len(modules) == 0 {
+		if _, ok := idx.paths[Normalize(modalias)]; ok || idx.builtin[Normalize(modalias)] {
+			modules = append(modules, Normalize(modalias))
+		}
+	}
+	return modules
+}
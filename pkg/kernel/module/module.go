@@ -1,8 +1,8 @@
 package module
 
 import (
+	"bytes"
 	"debug/elf"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io/fs"
@@ -11,6 +11,8 @@ import (
 	"strings"
 	"syscall"
 	"unsafe"
+
+	"chillos/pkg/decompress"
 )
 
 type Info struct {
@@ -18,19 +20,37 @@ type Info struct {
 	License string   `json:"license"`
 	Aliases []string `json:"aliases"`
 	Depends []string `json:"depends"`
+	SoftDep string   `json:"softdep,omitempty"`
 	Path    string   `json:"path"`
 }
 
+// Parse reads the .modinfo section of the module at path, which may be
+// compressed.
 func Parse(path string) (Info, error) {
-	f, err := os.OpenFile(path, os.O_RDONLY, 0)
-	if err != nil {
-		return Info{}, err
-	}
-	defer f.Close()
+	var (
+		e   *elf.File
+		err error
+	)
+	if decompress.IsCompressed(path) {
+		data, err := decompress.ReadFile(path)
+		if err != nil {
+			return Info{}, err
+		}
+		e, err = elf.NewFile(bytes.NewReader(data))
+		if err != nil {
+			return Info{}, fmt.Errorf("elf.Parse %v", err)
+		}
+	} else {
+		f, err := os.OpenFile(path, os.O_RDONLY, 0)
+		if err != nil {
+			return Info{}, err
+		}
+		defer f.Close()
 
-	e, err := elf.NewFile(f)
-	if err != nil {
-		return Info{}, fmt.Errorf("elf.Parse %v", err)
+		e, err = elf.NewFile(f)
+		if err != nil {
+			return Info{}, fmt.Errorf("elf.Parse %v", err)
+		}
 	}
 	defer e.Close()
 
@@ -67,6 +87,8 @@ func Parse(path string) (Info, error) {
 			if deps != "" {
 				m.Depends = strings.Split(deps, ",")
 			}
+		case strings.HasPrefix(en, "softdep="):
+			m.SoftDep = strings.TrimPrefix(en, "softdep=")
 		case strings.HasPrefix(en, "name="):
 			m.Name = strings.TrimPrefix(en, "name=")
 		}
@@ -76,16 +98,70 @@ func Parse(path string) (Info, error) {
 	return m, nil
 }
 
+const (
+	SysModulePath = "/sys/module"
+
+	// finit_module flags
+	MODULE_INIT_IGNORE_MODVERSIONS = 1
+	MODULE_INIT_IGNORE_VERMAGIC    = 2
+	MODULE_INIT_COMPRESSED_FILE    = 4
+)
+
+// Insert loads the module file at path. Plain modules are handed to the
+// kernel by descriptor with finit_module so it can check their signature
+// against the file, compressed ones are decompressed by the kernel where
+// it supports it and in memory otherwise.
 func Insert(path string, options string) error {
-	data, err := os.ReadFile(path)
+	file, err := os.Open(path)
 	if err != nil {
 		return err
 	}
+	defer file.Close()
 
-	p, _ := syscall.BytePtrFromString(options)
+	flags := 0
+	if decompress.IsCompressed(path) {
+		flags |= MODULE_INIT_COMPRESSED_FILE
+	}
 
+	err = finitModule(int(file.Fd()), options, flags)
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, syscall.ENOSYS),
+		flags&MODULE_INIT_COMPRESSED_FILE != 0 && (errors.Is(err, syscall.EINVAL) || errors.Is(err, syscall.EOPNOTSUPP)):
+		// no finit_module or no in-kernel decompression for this format
+	default:
+		return err
+	}
+
+	data, err := decompress.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return initModule(data, options)
+}
+
+func finitModule(fd int, options string, flags int) error {
+	p, err := syscall.BytePtrFromString(options)
+	if err != nil {
+		return err
+	}
+	if _, _, errno := syscall.Syscall(SYS_FINIT_MODULE, uintptr(fd), uintptr(unsafe.Pointer(p)), uintptr(flags)); errno != 0 {
+		return fmt.Errorf("syscall.FINIT_MODULE %w", errno)
+	}
+	return nil
+}
+
+func initModule(data []byte, options string) error {
+	if len(data) == 0 {
+		return fmt.Errorf("empty module")
+	}
+	p, err := syscall.BytePtrFromString(options)
+	if err != nil {
+		return err
+	}
 	if _, _, errno := syscall.Syscall(syscall.SYS_INIT_MODULE, uintptr(unsafe.Pointer(&data[0])), uintptr(len(data)), uintptr(unsafe.Pointer(p))); errno != 0 {
-		return fmt.Errorf("syscall.INIT_MODULE %v", errno)
+		return fmt.Errorf("syscall.INIT_MODULE %w", errno)
 	}
 	return nil
 }
@@ -98,43 +174,132 @@ func Delete(name string, flags int) error {
 	return nil
 }
 
-func Load(path string, searchPath string, loaded map[string]bool) error {
-	if !strings.HasPrefix(path, "/") {
-		var err error
-		path, err = Search(path, searchPath)
+// IsLoaded reports whether a loadable module is present in the kernel,
+// built-in modules have no initstate.
+func IsLoaded(name string) bool {
+	_, err := os.Stat(filepath.Join(SysModulePath, Normalize(name), "initstate"))
+	return err == nil
+}
+
+// Load loads a module by name through the index in searchPath, or a
+// module file by absolute path, together with its dependencies. options
+// are passed after the configured ones, config may be nil.
+func Load(name, options, searchPath string, config *Config, loaded map[string]bool) error {
+	if !strings.HasPrefix(name, "/") {
+		idx, err := LoadIndex(searchPath)
 		if err != nil {
 			return err
 		}
+		idx.SetConfig(config)
+		return idx.Load(name, options, loaded)
 	}
 
-	if _, ok := loaded[path]; ok {
+	// without an index dependencies are found by walking searchPath
+	idx, _ := LoadIndex(searchPath)
+	return loadFile(name, options, searchPath, idx, config, loaded)
+}
+
+// loadFile loads the module file name after its dependencies, found
+// through idx when it is not nil.
+func loadFile(name, options, searchPath string, idx *Index, config *Config, loaded map[string]bool) error {
+	if loaded[name] {
 		return nil
 	}
+	loaded[name] = true
 
-	info, err := Parse(path)
+	info, err := Parse(name)
 	if err != nil {
 		return err
 	}
 
 	for _, dep := range info.Depends {
-		if err := Load(dep, searchPath, loaded); err != nil {
+		if IsLoaded(dep) {
+			continue
+		}
+		path, err := search(dep, searchPath, idx)
+		if err != nil {
+			return fmt.Errorf("%s: %v", dep, err)
+		}
+		if err := loadFile(path, "", searchPath, idx, config, loaded); err != nil {
 			return err
 		}
 	}
 
-	if err := Insert(path, ""); err != nil {
+	if info.Name == "" {
+		info.Name = NameOf(name)
+	}
+	options = joinOptions(config.ModuleOptions(info.Name), options)
+	if err := Insert(name, options); err != nil && !errors.Is(err, syscall.EEXIST) {
 		return err
 	}
 	return nil
 }
 
+// Load inserts name after its pre soft dependencies and dependencies, and
+// before its post soft dependencies. Built-in and already loaded modules
+// are skipped, options are passed after the configured ones.
+func (idx *Index) Load(name, options string, loaded map[string]bool) error {
+	name = Normalize(name)
+	if loaded[name] || idx.IsBuiltin(name) || IsLoaded(name) {
+		return nil
+	}
+	loaded[name] = true
+
+	path, ok := idx.Path(name)
+	if !ok {
+		return fmt.Errorf("%s: %w", name, ErrNotFound)
+	}
+
+	softdep := idx.SoftDep(name)
+	for _, pre := range softdep.Pre {
+		_ = idx.Load(pre, "", loaded)
+	}
+
+	for _, dep := range idx.Dependencies(name) {
+		depName := NameOf(dep)
+		if loaded[depName] || IsLoaded(depName) {
+			continue
+		}
+		loaded[depName] = true
+		if err := Insert(dep, idx.config.ModuleOptions(depName)); err != nil && !errors.Is(err, syscall.EEXIST) {
+			return fmt.Errorf("%s: %v", depName, err)
+		}
+	}
+
+	options = joinOptions(idx.config.ModuleOptions(name), options)
+	if err := Insert(path, options); err != nil && !errors.Is(err, syscall.EEXIST) {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+
+	for _, post := range softdep.Post {
+		_ = idx.Load(post, "", loaded)
+	}
+	return nil
+}
+
+// Search finds the module file for name, through the index when there is
+// one and by walking searchPath otherwise.
 func Search(name string, searchPath string) (string, error) {
+	idx, _ := LoadIndex(searchPath)
+	return search(name, searchPath, idx)
+}
+
+// search is Search through an already loaded index, a nil idx walks
+// searchPath.
+func search(name, searchPath string, idx *Index) (string, error) {
+	if idx != nil {
+		if path, ok := idx.Path(name); ok {
+			return path, nil
+		}
+		return "", ErrNotFound
+	}
+
 	var p string
 	if err := filepath.Walk(searchPath, func(path string, info fs.FileInfo, err error) error {
-		if err != nil || info.IsDir() || !strings.HasSuffix(path, ".ko") {
+		if err != nil || info.IsDir() || !IsModuleFile(path) {
 			return err
 		}
-		if filepath.Base(path) == name+".ko" {
+		if NameOf(path) == Normalize(name) {
 			p = path
 			return filepath.SkipAll
 		}
@@ -144,20 +309,7 @@ func Search(name string, searchPath string) (string, error) {
 	}
 
 	if p == "" {
-		return "", errors.New("not found")
+		return "", ErrNotFound
 	}
 	return p, nil
 }
-
-func LoadCache(searchPath string) ([]Info, error) {
-	data, err := os.ReadFile(filepath.Join(searchPath, "cache.json"))
-	if err != nil {
-		return nil, err
-	}
-
-	var is []Info
-	if err := json.Unmarshal(data, &is); err != nil {
-		return nil, err
-	}
-	return is, nil
-}
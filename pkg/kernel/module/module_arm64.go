@@ -0,0 +1,5 @@
+package module
+
+const (
+	SYS_FINIT_MODULE = 273
+)
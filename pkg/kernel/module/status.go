@@ -0,0 +1,216 @@
+package module
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+const (
+	ProcModulesPath = "/proc/modules"
+)
+
+var (
+	ErrNotLoaded = errors.New("module is not loaded")
+	ErrInUse     = errors.New("module is in use")
+	ErrBuiltin   = errors.New("module is built-in")
+)
+
+// Module is a module loaded in the kernel as /proc/modules and
+// /sys/module describe it.
+type Module struct {
+	Name       string            `json:"name"`
+	Size       int               `json:"size"`
+	RefCount   int               `json:"refcount"` // -1 when the module cannot be unloaded
+	UsedBy     []string          `json:"used-by"`
+	Holders    []string          `json:"holders"`
+	State      string            `json:"state"`
+	Taint      string            `json:"taint"`
+	Parameters map[string]string `json:"parameters"`
+}
+
+// List returns the loaded modules, most recently loaded first.
+func List() ([]Module, error) {
+	data, err := os.ReadFile(ProcModulesPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var modules []Module
+	for _, line := range strings.Split(string(data), "\n") {
+		if line == "" {
+			continue
+		}
+		m, err := parseProcModule(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", ProcModulesPath, err)
+		}
+		m.readSysfs()
+		modules = append(modules, m)
+	}
+	return modules, nil
+}
+
+// Status returns the loaded module name.
+func Status(name string) (Module, error) {
+	modules, err := List()
+	if err != nil {
+		return Module{}, err
+	}
+	name = Normalize(name)
+	for _, m := range modules {
+		if m.Name == name {
+			return m, nil
+		}
+	}
+	if isBuiltin(name) {
+		return Module{}, fmt.Errorf("%s: %w", name, ErrBuiltin)
+	}
+	return Module{}, fmt.Errorf("%s: %w", name, ErrNotLoaded)
+}
+
+// parseProcModule parses a /proc/modules line,
+// name size refcount used,by, state address [(taint)]
+func parseProcModule(line string) (Module, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 5 {
+		return Module{}, fmt.Errorf("invalid line %q", line)
+	}
+
+	m := Module{Name: fields[0], State: fields[4], RefCount: -1}
+	size, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return Module{}, fmt.Errorf("invalid size %q", fields[1])
+	}
+	m.Size = size
+	if refcount, err := strconv.Atoi(fields[2]); err == nil {
+		m.RefCount = refcount
+	}
+	if fields[3] != "-" {
+		for _, user := range strings.Split(fields[3], ",") {
+			if user != "" {
+				m.UsedBy = append(m.UsedBy, user)
+			}
+		}
+	}
+	if len(fields) > 6 {
+		m.Taint = strings.Trim(fields[6], "()")
+	}
+	return m, nil
+}
+
+func (m *Module) readSysfs() {
+	dir := filepath.Join(SysModulePath, m.Name)
+
+	if entries, err := os.ReadDir(filepath.Join(dir, "holders")); err == nil {
+		for _, entry := range entries {
+			m.Holders = append(m.Holders, entry.Name())
+		}
+	}
+
+	if data, err := os.ReadFile(filepath.Join(dir, "taint")); err == nil {
+		if taint := strings.TrimSpace(string(data)); taint != "" {
+			m.Taint = taint
+		}
+	}
+
+	entries, err := os.ReadDir(filepath.Join(dir, "parameters"))
+	if err != nil {
+		return
+	}
+	m.Parameters = map[string]string{}
+	for _, entry := range entries {
+		// some parameters are write only
+		data, err := os.ReadFile(filepath.Join(dir, "parameters", entry.Name()))
+		if err != nil {
+			continue
+		}
+		m.Parameters[entry.Name()] = strings.TrimSpace(string(data))
+	}
+}
+
+// Users returns every module holding m.
+func (m Module) Users() []string {
+	users := slices.Clone(m.UsedBy)
+	for _, holder := range m.Holders {
+		if !slices.Contains(users, holder) {
+			users = append(users, holder)
+		}
+	}
+	return users
+}
+
+// Unload removes name from the kernel. When recursive the modules it
+// used are removed afterwards as well, unless something else still uses
+// them.
+func Unload(name string, recursive bool) error {
+	m, err := Status(name)
+	if err != nil {
+		return err
+	}
+
+	// the modules name holds, found before it goes away
+	var deps []string
+	if recursive {
+		modules, err := List()
+		if err != nil {
+			return err
+		}
+		for _, dep := range modules {
+			if slices.Contains(dep.Users(), m.Name) {
+				deps = append(deps, dep.Name)
+			}
+		}
+	}
+
+	if err := unload(m); err != nil {
+		return err
+	}
+
+	for _, dep := range deps {
+		dm, err := Status(dep)
+		if err != nil || dm.RefCount != 0 || len(dm.Users()) != 0 {
+			continue
+		}
+		if err := Unload(dep, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func unload(m Module) error {
+	if users := m.Users(); len(users) != 0 {
+		return fmt.Errorf("%s: %w by %s", m.Name, ErrInUse, strings.Join(users, ", "))
+	}
+	if m.RefCount > 0 {
+		return fmt.Errorf("%s: %w, %d references", m.Name, ErrInUse, m.RefCount)
+	}
+
+	err := Delete(m.Name, syscall.O_NONBLOCK)
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, syscall.EWOULDBLOCK), errors.Is(err, syscall.EBUSY):
+		return fmt.Errorf("%s: %w", m.Name, ErrInUse)
+	case errors.Is(err, syscall.ENOENT):
+		return fmt.Errorf("%s: %w", m.Name, ErrNotLoaded)
+	case errors.Is(err, syscall.EPERM):
+		return fmt.Errorf("%s: not permitted to unload", m.Name)
+	}
+	return fmt.Errorf("%s: %w", m.Name, err)
+}
+
+// isBuiltin reports whether name is built into the running kernel, which
+// sysfs only tells for built-in modules with parameters or a version.
+func isBuiltin(name string) bool {
+	if _, err := os.Stat(filepath.Join(SysModulePath, name)); err != nil {
+		return false
+	}
+	return !IsLoaded(name)
+}
@@ -0,0 +1,121 @@
+/*
+ * Copyright (c) 2025 Manjeet Singh <itsmanjeet1998@gmail.com>.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 3.
+ *
+ * This program is distributed in the hope that it will be useful, but
+ * WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+ * General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+package mount
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"syscall"
+)
+
+var (
+	flagOptions = map[string]uintptr{
+		"ro":          syscall.MS_RDONLY,
+		"nosuid":      syscall.MS_NOSUID,
+		"nodev":       syscall.MS_NODEV,
+		"noexec":      syscall.MS_NOEXEC,
+		"sync":        syscall.MS_SYNCHRONOUS,
+		"dirsync":     syscall.MS_DIRSYNC,
+		"remount":     syscall.MS_REMOUNT,
+		"bind":        syscall.MS_BIND,
+		"rbind":       syscall.MS_BIND | syscall.MS_REC,
+		"noatime":     syscall.MS_NOATIME,
+		"nodiratime":  syscall.MS_NODIRATIME,
+		"relatime":    syscall.MS_RELATIME,
+		"strictatime": syscall.MS_STRICTATIME,
+		"silent":      syscall.MS_SILENT,
+	}
+
+	clearOptions = map[string]uintptr{
+		"rw":       syscall.MS_RDONLY,
+		"suid":     syscall.MS_NOSUID,
+		"dev":      syscall.MS_NODEV,
+		"exec":     syscall.MS_NOEXEC,
+		"async":    syscall.MS_SYNCHRONOUS,
+		"atime":    syscall.MS_NOATIME,
+		"diratime": syscall.MS_NODIRATIME,
+	}
+
+	propagationOptions = map[string]uintptr{
+		"private":     syscall.MS_PRIVATE,
+		"rprivate":    syscall.MS_PRIVATE | syscall.MS_REC,
+		"shared":      syscall.MS_SHARED,
+		"rshared":     syscall.MS_SHARED | syscall.MS_REC,
+		"slave":       syscall.MS_SLAVE,
+		"rslave":      syscall.MS_SLAVE | syscall.MS_REC,
+		"unbindable":  syscall.MS_UNBINDABLE,
+		"runbindable": syscall.MS_UNBINDABLE | syscall.MS_REC,
+	}
+)
+
+// ParseOptions splits fstab style options into mount flags, propagation
+// flags and the filesystem specific data string.
+func ParseOptions(options []string) (flags, propagation uintptr, data string) {
+	var extra []string
+	for _, option := range options {
+		if f, ok := flagOptions[option]; ok {
+			flags |= f
+		} else if f, ok := clearOptions[option]; ok {
+			flags &^= f
+		} else if f, ok := propagationOptions[option]; ok {
+			propagation |= f
+		} else if option != "defaults" && option != "" {
+			extra = append(extra, option)
+		}
+	}
+	return flags, propagation, strings.Join(extra, ",")
+}
+
+// Mount mounts source on target, creating target if required.
+func Mount(source, target, fstype string, options []string) error {
+	flags, propagation, data := ParseOptions(options)
+
+	if err := os.MkdirAll(target, 0755); err != nil {
+		return err
+	}
+
+	if err := syscall.Mount(source, target, fstype, flags, data); err != nil {
+		return fmt.Errorf("mount %s on %s: %w", source, target, err)
+	}
+
+	// read-only bind mounts need a second pass, the kernel ignores
+	// MS_RDONLY on the initial bind
+	if flags&syscall.MS_BIND != 0 && flags&syscall.MS_RDONLY != 0 {
+		if err := syscall.Mount("", target, "", flags|syscall.MS_REMOUNT, ""); err != nil {
+			return fmt.Errorf("remount %s read-only: %w", target, err)
+		}
+	}
+
+	if propagation != 0 {
+		if err := syscall.Mount("", target, "", propagation, ""); err != nil {
+			return fmt.Errorf("set propagation of %s: %w", target, err)
+		}
+	}
+	return nil
+}
+
+func IsMounted(target string) (bool, error) {
+	mounts, err := ReadMountInfo()
+	if err != nil {
+		return false, err
+	}
+	return slices.ContainsFunc(mounts, func(m Info) bool {
+		return m.MountPoint == target
+	}), nil
+}
@@ -0,0 +1,133 @@
+/*
+ * Copyright (c) 2025 Manjeet Singh <itsmanjeet1998@gmail.com>.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 3.
+ *
+ * This program is distributed in the hope that it will be useful, but
+ * WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+ * General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package mount
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	MountInfoPath = "/proc/self/mountinfo"
+)
+
+type Info struct {
+	ID           int
+	Parent       int
+	Major        int
+	Minor        int
+	Root         string
+	MountPoint   string
+	Options      string
+	Optional     []string
+	Type         string
+	Source       string
+	SuperOptions string
+}
+
+func ReadMountInfo() ([]Info, error) {
+	file, err := os.Open(MountInfoPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ParseMountInfo(file)
+}
+
+// ParseMountInfo parses the proc(5) mountinfo format, mounts are returned
+// in the order they were mounted.
+func ParseMountInfo(r io.Reader) ([]Info, error) {
+	var mounts []Info
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		separator := -1
+		for i, field := range fields {
+			if field == "-" && i >= 6 {
+				separator = i
+				break
+			}
+		}
+		if separator == -1 || len(fields) < separator+3 {
+			return nil, fmt.Errorf("invalid mountinfo line %q", line)
+		}
+
+		var (
+			info Info
+			err  error
+		)
+		if info.ID, err = strconv.Atoi(fields[0]); err != nil {
+			return nil, fmt.Errorf("invalid mount id %q", fields[0])
+		}
+		if info.Parent, err = strconv.Atoi(fields[1]); err != nil {
+			return nil, fmt.Errorf("invalid parent id %q", fields[1])
+		}
+		if _, err := fmt.Sscanf(fields[2], "%d:%d", &info.Major, &info.Minor); err != nil {
+			return nil, fmt.Errorf("invalid device %q", fields[2])
+		}
+
+		info.Root = unescape(fields[3])
+		info.MountPoint = unescape(fields[4])
+		info.Options = fields[5]
+		info.Optional = fields[6:separator]
+		info.Type = fields[separator+1]
+		info.Source = unescape(fields[separator+2])
+		if len(fields) > separator+3 {
+			info.SuperOptions = fields[separator+3]
+		}
+
+		mounts = append(mounts, info)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return mounts, nil
+}
+
+// unescape decodes the octal escapes the kernel uses for spaces, tabs,
+// newlines and backslashes.
+func unescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+4 <= len(s) {
+			if v, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				sb.WriteByte(byte(v))
+				i += 3
+				continue
+			}
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String()
+}
@@ -0,0 +1,37 @@
+/*
+ * Copyright (c) 2025 Manjeet Singh <itsmanjeet1998@gmail.com>.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 3.
+ *
+ * This program is distributed in the hope that it will be useful, but
+ * WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+ * General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package poll
+
+import (
+	"chillos/pkg/event"
+	"chillos/pkg/kernel/drm"
+)
+
+// Card is the event source of a DRM card, it delivers a drm.VBlank for
+// every page flip queued with drm.PageFlipEvent once it completed.
+type Card struct {
+	*drm.Card
+}
+
+func (c Card) Read() (event.Event, error) {
+	ev, err := c.ReadEvent()
+	if err != nil {
+		return nil, err
+	}
+	return ev, nil
+}
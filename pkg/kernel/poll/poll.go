@@ -20,16 +20,22 @@ package poll
 import (
 	"errors"
 	"fmt"
+	"io"
+	"slices"
+	"sync"
 	"syscall"
 
 	"chillos/pkg/event"
 )
 
+// Listener waits on sources with epoll. Sources may be added and removed
+// from other goroutines while it polls.
 type Listener struct {
 	sources []event.Source
 	fdsrc   map[int]event.Source
 	efd     int
 	timeout int
+	mutex   sync.Mutex
 }
 
 var (
@@ -54,8 +60,11 @@ func (l *Listener) Close() error {
 }
 
 func (l *Listener) Add(source event.Source) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
 	ev := &syscall.EpollEvent{
-		Events: syscall.EPOLLIN,
+		Events: syscall.EPOLLIN | syscall.EPOLLRDHUP,
 		Fd:     int32(source.Fd()),
 	}
 	_ = syscall.SetNonblock(source.Fd(), true)
@@ -69,9 +78,44 @@ func (l *Listener) Add(source event.Source) error {
 	return nil
 }
 
+func (l *Listener) Remove(source event.Source) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return l.remove(source)
+}
+
+func (l *Listener) remove(source event.Source) error {
+	if _, ok := l.fdsrc[source.Fd()]; !ok {
+		return nil
+	}
+
+	delete(l.fdsrc, source.Fd())
+	for i, s := range l.sources {
+		if s == source {
+			l.sources = append(l.sources[:i], l.sources[i+1:]...)
+			break
+		}
+	}
+
+	if err := syscall.EpollCtl(l.efd, syscall.EPOLL_CTL_DEL, source.Fd(), nil); err != nil {
+		return fmt.Errorf("remove event from epoll %v: %w", source.Fd(), err)
+	}
+	return nil
+}
+
 func (l *Listener) Poll() ([]event.Event, error) {
-	epev := make([]syscall.EpollEvent, len(l.sources))
-	n, err := syscall.EpollWait(l.efd, epev, l.timeout)
+	return l.PollTimeout(l.timeout)
+}
+
+// PollTimeout is Poll waiting at most timeout milliseconds, or until an
+// event arrives when negative.
+func (l *Listener) PollTimeout(timeout int) ([]event.Event, error) {
+	l.mutex.Lock()
+	epev := make([]syscall.EpollEvent, max(len(l.sources), 1))
+	l.mutex.Unlock()
+
+	n, err := syscall.EpollWait(l.efd, epev, timeout)
 	if err != nil {
 		return nil, err
 	}
@@ -79,17 +123,50 @@ func (l *Listener) Poll() ([]event.Event, error) {
 	var events []event.Event
 
 	for i := 0; i < n; i++ {
+		l.mutex.Lock()
 		src, ok := l.fdsrc[int(epev[i].Fd)]
-		if ok {
-			ev, err := src.Read()
-			if err == nil {
-				events = append(events, ev)
-			}
+		l.mutex.Unlock()
+		if !ok {
+			continue
+		}
+
+		ev, err := src.Read()
+		if err == nil {
+			events = append(events, ev)
+			continue
+		}
+
+		if !isHangup(err, epev[i].Events) {
+			continue
+		}
+
+		// nothing more will ever come, stop the level triggered epoll
+		// from spinning on it
+		l.mutex.Lock()
+		_ = l.remove(src)
+		l.mutex.Unlock()
+		if f, ok := src.(event.Finisher); ok {
+			events = append(events, f.Finish())
 		}
 	}
 	return events, nil
 }
 
+// isHangup reports whether a failed read means the source is gone, at
+// its end or failing after the other side hung up.
+func isHangup(err error, events uint32) bool {
+	if errors.Is(err, io.EOF) {
+		return true
+	}
+	if events&(syscall.EPOLLHUP|syscall.EPOLLERR|syscall.EPOLLRDHUP) == 0 {
+		return false
+	}
+	return !errors.Is(err, syscall.EAGAIN) && !errors.Is(err, syscall.EINTR)
+}
+
 func (l *Listener) Sources() []event.Source {
-	return l.sources
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return slices.Clone(l.sources)
 }
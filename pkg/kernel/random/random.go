@@ -0,0 +1,107 @@
+/*
+ * Copyright (c) 2025 Manjeet Singh <itsmanjeet1998@gmail.com>.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 3.
+ *
+ * This program is distributed in the hope that it will be useful, but
+ * WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+ * General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+package random
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"unsafe"
+
+	"chillos/pkg/kernel/ioctl"
+)
+
+const (
+	DevicePath = "/dev/urandom"
+
+	// SeedSize matches the input pool of the kernel CRNG
+	SeedSize = 512
+)
+
+var (
+	IoctlAddEntropy = ioctl.IOW('R', 0x03, 2*int(unsafe.Sizeof(int32(0))))
+)
+
+// AddEntropy mixes seed into the kernel pool, credit is the number of
+// bits the kernel may count as entropy.
+func AddEntropy(seed []byte, credit int) error {
+	file, err := os.OpenFile(DevicePath, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// struct rand_pool_info { int entropy_count; int buf_size; __u32 buf[]; }
+	info := make([]byte, 8+len(seed))
+	binary.NativeEndian.PutUint32(info[0:], uint32(credit))
+	binary.NativeEndian.PutUint32(info[4:], uint32(len(seed)))
+	copy(info[8:], seed)
+
+	return ioctl.Call(file.Fd(), uintptr(IoctlAddEntropy), unsafe.Pointer(&info[0]))
+}
+
+// LoadSeed credits the seed saved at path and immediately replaces it, a
+// seed must never be credited twice.
+func LoadSeed(path string) error {
+	seed, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if len(seed) == 0 {
+		return fmt.Errorf("empty random seed %s", path)
+	}
+
+	if err := AddEntropy(seed, len(seed)*8); err != nil {
+		return fmt.Errorf("failed to credit random seed: %w", err)
+	}
+	clear(seed)
+
+	return SaveSeed(path)
+}
+
+// SaveSeed writes a fresh seed taken from the kernel pool to path.
+func SaveSeed(path string) error {
+	seed := make([]byte, SeedSize)
+	if _, err := rand.Read(seed); err != nil {
+		return err
+	}
+	defer clear(seed)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(seed); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,91 @@
+/*
+ * Copyright (c) 2025 Manjeet Singh <itsmanjeet1998@gmail.com>.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 3.
+ *
+ * This program is distributed in the hope that it will be useful, but
+ * WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+ * General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+package rtc
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"chillos/pkg/kernel/ioctl"
+)
+
+const (
+	DevicePath = "/dev/rtc0"
+)
+
+// sysTime is struct rtc_time, same layout as struct tm
+type sysTime struct {
+	sec   int32
+	min   int32
+	hour  int32
+	mday  int32
+	mon   int32
+	year  int32
+	wday  int32
+	yday  int32
+	isdst int32
+}
+
+var (
+	IoctlReadTime = ioctl.IOR('p', 0x09, int(unsafe.Sizeof(sysTime{})))
+	IoctlSetTime  = ioctl.IOW('p', 0x0a, int(unsafe.Sizeof(sysTime{})))
+)
+
+// RTC is a hardware clock, it is assumed to keep UTC.
+type RTC struct {
+	file *os.File
+}
+
+func Open(path string) (*RTC, error) {
+	file, err := os.OpenFile(path, os.O_RDONLY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open rtc %s: %w", path, err)
+	}
+	return &RTC{file: file}, nil
+}
+
+func (r *RTC) Close() error {
+	return r.file.Close()
+}
+
+func (r *RTC) Read() (time.Time, error) {
+	var t sysTime
+	if err := ioctl.Call(r.file.Fd(), uintptr(IoctlReadTime), unsafe.Pointer(&t)); err != nil {
+		return time.Time{}, err
+	}
+	return time.Date(int(t.year)+1900, time.Month(t.mon+1), int(t.mday),
+		int(t.hour), int(t.min), int(t.sec), 0, time.UTC), nil
+}
+
+func (r *RTC) Set(now time.Time) error {
+	now = now.UTC()
+	t := sysTime{
+		sec:   int32(now.Second()),
+		min:   int32(now.Minute()),
+		hour:  int32(now.Hour()),
+		mday:  int32(now.Day()),
+		mon:   int32(now.Month()) - 1,
+		year:  int32(now.Year()) - 1900,
+		wday:  int32(now.Weekday()),
+		yday:  int32(now.YearDay()) - 1,
+		isdst: 0,
+	}
+	return ioctl.Call(r.file.Fd(), uintptr(IoctlSetTime), unsafe.Pointer(&t))
+}
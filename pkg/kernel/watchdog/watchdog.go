@@ -0,0 +1,107 @@
+/*
+ * Copyright (c) 2025 Manjeet Singh <itsmanjeet1998@gmail.com>.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 3.
+ *
+ * This program is distributed in the hope that it will be useful, but
+ * WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+ * General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+package watchdog
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+
+	"chillos/pkg/kernel/ioctl"
+)
+
+const (
+	DevicePath = "/dev/watchdog"
+
+	optionDisableCard = 0x0001
+	optionEnableCard  = 0x0002
+
+	// writing it right before close stops the timer, unless the driver
+	// was built with nowayout
+	magicClose = "V"
+)
+
+var (
+	IoctlSetOptions = ioctl.IOR('W', 4, int(unsafe.Sizeof(int32(0))))
+	IoctlKeepalive  = ioctl.IOR('W', 5, int(unsafe.Sizeof(int32(0))))
+	IoctlSetTimeout = ioctl.IOWR('W', 6, int(unsafe.Sizeof(int32(0))))
+	IoctlGetTimeout = ioctl.IOR('W', 7, int(unsafe.Sizeof(int32(0))))
+)
+
+// Watchdog is an open watchdog device, the timer starts running as soon
+// as it is opened and the machine resets unless Keepalive is called
+// within Timeout.
+type Watchdog struct {
+	file *os.File
+}
+
+func Open(path string) (*Watchdog, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open watchdog %s: %w", path, err)
+	}
+	return &Watchdog{file: file}, nil
+}
+
+func (w *Watchdog) Keepalive() error {
+	var v int32
+	return ioctl.Call(w.file.Fd(), uintptr(IoctlKeepalive), unsafe.Pointer(&v))
+}
+
+// SetTimeout changes the timeout in seconds, drivers may round it so the
+// effective value is returned.
+func (w *Watchdog) SetTimeout(seconds int) (int, error) {
+	v := int32(seconds)
+	if err := ioctl.Call(w.file.Fd(), uintptr(IoctlSetTimeout), unsafe.Pointer(&v)); err != nil {
+		return 0, err
+	}
+	return int(v), nil
+}
+
+func (w *Watchdog) Timeout() (int, error) {
+	var v int32
+	if err := ioctl.Call(w.file.Fd(), uintptr(IoctlGetTimeout), unsafe.Pointer(&v)); err != nil {
+		return 0, err
+	}
+	return int(v), nil
+}
+
+func (w *Watchdog) Enable() error {
+	v := int32(optionEnableCard)
+	return ioctl.Call(w.file.Fd(), uintptr(IoctlSetOptions), unsafe.Pointer(&v))
+}
+
+func (w *Watchdog) Disable() error {
+	v := int32(optionDisableCard)
+	return ioctl.Call(w.file.Fd(), uintptr(IoctlSetOptions), unsafe.Pointer(&v))
+}
+
+// Close releases the device leaving the timer running.
+func (w *Watchdog) Close() error {
+	return w.file.Close()
+}
+
+// Stop disarms the watchdog and closes the device.
+func (w *Watchdog) Stop() error {
+	_ = w.Disable()
+	if _, err := w.file.WriteString(magicClose); err != nil {
+		w.file.Close()
+		return err
+	}
+	return w.file.Close()
+}
@@ -21,3 +21,10 @@ type Job interface {
 	Id() string
 	Do()
 }
+
+// Sequenced jobs are ordered by their sequence number instead of the
+// order they were submitted in.
+type Sequenced interface {
+	Job
+	Sequence() uint64
+}
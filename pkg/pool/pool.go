@@ -32,8 +32,12 @@ type Pool struct {
 }
 
 func CreatePool(count int) *Pool {
+	return newPool(count, 50)
+}
+
+func newPool(count, capacity int) *Pool {
 	return &Pool{
-		jobQueue: make(chan job.Job, 50),
+		jobQueue: make(chan job.Job, capacity),
 		workers:  count,
 		stop:     make(chan struct{}),
 	}
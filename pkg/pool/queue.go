@@ -0,0 +1,165 @@
+/*
+ * Copyright (c) 2025 Manjeet Singh <itsmanjeet1998@gmail.com>.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 3.
+ *
+ * This program is distributed in the hope that it will be useful, but
+ * WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+ * General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+package pool
+
+import (
+	"sync"
+
+	"chillos/pkg/pool/job"
+)
+
+// Queue runs jobs on a pool while keeping conflicting jobs in order: a job
+// only starts once every conflicting job queued before it has finished,
+// unrelated jobs run in parallel.
+type Queue struct {
+	pool     *Pool
+	limit    int
+	conflict func(a, b job.Job) bool
+
+	mutex   sync.Mutex
+	changed *sync.Cond
+	jobs    []*queued // waiting and running, in order
+}
+
+type queued struct {
+	job     job.Job
+	running bool
+}
+
+type queuedJob struct {
+	*queued
+	queue *Queue
+}
+
+func (j queuedJob) Id() string {
+	return j.job.Id()
+}
+
+func (j queuedJob) Do() {
+	defer j.queue.finish(j.queued)
+	j.job.Do()
+}
+
+// NewQueue creates a queue holding at most limit jobs, Submit blocks
+// while it is full.
+func NewQueue(workers, limit int, conflict func(a, b job.Job) bool) *Queue {
+	q := &Queue{
+		// running jobs never exceed limit so handing them to the pool
+		// never blocks
+		pool:     newPool(workers, limit),
+		limit:    limit,
+		conflict: conflict,
+	}
+	q.changed = sync.NewCond(&q.mutex)
+	return q
+}
+
+func (q *Queue) Start() {
+	q.pool.Start()
+}
+
+func (q *Queue) Submit(j job.Job) {
+	q.mutex.Lock()
+	for len(q.jobs) >= q.limit {
+		q.changed.Wait()
+	}
+
+	entry := &queued{job: j}
+	index := len(q.jobs)
+	if s, ok := j.(job.Sequenced); ok && s.Sequence() != 0 {
+		// late arrivals go before anything waiting with a higher
+		// sequence number, running jobs are left alone
+		for index > 0 {
+			prev, ok := q.jobs[index-1].job.(job.Sequenced)
+			if !ok || q.jobs[index-1].running || prev.Sequence() <= s.Sequence() {
+				break
+			}
+			index--
+		}
+	}
+	q.jobs = append(q.jobs, nil)
+	copy(q.jobs[index+1:], q.jobs[index:])
+	q.jobs[index] = entry
+
+	ready := q.ready()
+	q.mutex.Unlock()
+
+	q.dispatch(ready)
+}
+
+// Len is the number of waiting and running jobs.
+func (q *Queue) Len() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	return len(q.jobs)
+}
+
+// WaitIdle blocks until the queue is empty.
+func (q *Queue) WaitIdle() {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	for len(q.jobs) > 0 {
+		q.changed.Wait()
+	}
+}
+
+func (q *Queue) finish(entry *queued) {
+	q.mutex.Lock()
+	for i, e := range q.jobs {
+		if e == entry {
+			q.jobs = append(q.jobs[:i], q.jobs[i+1:]...)
+			break
+		}
+	}
+	ready := q.ready()
+	q.changed.Broadcast()
+	q.mutex.Unlock()
+
+	q.dispatch(ready)
+}
+
+// ready marks and returns the waiting jobs no earlier job conflicts with,
+// must be called locked.
+func (q *Queue) ready() []*queued {
+	var ready []*queued
+	for i, entry := range q.jobs {
+		if entry.running {
+			continue
+		}
+
+		blocked := false
+		for _, before := range q.jobs[:i] {
+			if q.conflict(before.job, entry.job) {
+				blocked = true
+				break
+			}
+		}
+		if !blocked {
+			entry.running = true
+			ready = append(ready, entry)
+		}
+	}
+	return ready
+}
+
+func (q *Queue) dispatch(ready []*queued) {
+	for _, entry := range ready {
+		q.pool.Submit(queuedJob{queued: entry, queue: q})
+	}
+}
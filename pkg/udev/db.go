@@ -0,0 +1,96 @@
+/*
+ * Copyright (c) 2025 Manjeet Singh <itsmanjeet1998@gmail.com>.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 3.
+ *
+ * This program is distributed in the hope that it will be useful, but
+ * WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+ * General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+package udev
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// DatabasePath keeps the last processed event of every device that
+	// is currently present, one file per device path.
+	DatabasePath = "/cache/udev/data"
+)
+
+func databaseFile(devpath string) string {
+	return filepath.Join(DatabasePath, strings.ReplaceAll(strings.TrimPrefix(devpath, "/"), "/", "!"))
+}
+
+func WriteDevice(e Event) error {
+	if err := os.MkdirAll(DatabasePath, 0755); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	path := databaseFile(e.DevicePath)
+	if err := os.WriteFile(path+".tmp", data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+func ReadDevice(devpath string) (Event, error) {
+	var e Event
+	data, err := os.ReadFile(databaseFile(devpath))
+	if err != nil {
+		return e, err
+	}
+	err = json.Unmarshal(data, &e)
+	return e, err
+}
+
+func RemoveDevice(devpath string) error {
+	err := os.Remove(databaseFile(devpath))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// Devices returns every device in the database.
+func Devices() ([]Event, error) {
+	entries, err := os.ReadDir(DatabasePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var devices []Event
+	for _, entry := range entries {
+		if strings.HasSuffix(entry.Name(), ".tmp") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(DatabasePath, entry.Name()))
+		if err != nil {
+			continue
+		}
+		var e Event
+		if err := json.Unmarshal(data, &e); err == nil {
+			devices = append(devices, e)
+		}
+	}
+	return devices, nil
+}
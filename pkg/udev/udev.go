@@ -0,0 +1,161 @@
+/*
+ * Copyright (c) 2025 Manjeet Singh <itsmanjeet1998@gmail.com>.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 3.
+ *
+ * This program is distributed in the hope that it will be useful, but
+ * WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+ * General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+// Package udev talks to service/udevd, which republishes uevents once
+// rules and permissions have been applied to the device.
+package udev
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"syscall"
+	"time"
+
+	"chillos/pkg/connect"
+	"chillos/pkg/event"
+)
+
+const (
+	// SocketID is the pkg/connect id udevd listens on
+	SocketID = "udevd"
+
+	maxMessageSize = 64 * 1024
+)
+
+type Event struct {
+	Action     string            `json:"action"`
+	DevicePath string            `json:"devpath"`
+	Subsystem  string            `json:"subsystem"`
+	DeviceName string            `json:"devname,omitempty"`
+	DeviceNode string            `json:"devnode,omitempty"`
+	Properties map[string]string `json:"properties,omitempty"`
+	Symlinks   []string          `json:"symlinks,omitempty"`
+	Sequence   uint64            `json:"seqnum,omitempty"`
+}
+
+func (e Event) Event() {}
+
+// Subscription is sent by clients right after connecting, an empty list
+// subscribes to every subsystem.
+type Subscription struct {
+	Subsystems []string `json:"subsystems"`
+}
+
+// Monitor receives device events from udevd, it implements event.Source
+// so it can be added to a poll.Listener next to input devices.
+type Monitor struct {
+	conn *connect.Connection
+	fd   int
+	buf  []byte
+}
+
+func NewMonitor(subsystems ...string) (*Monitor, error) {
+	conn, err := connect.Connect(SocketID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := conn.Send("subscribe", Subscription{Subsystems: subsystems}, nil); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("failed to subscribe: %v", err)
+	}
+
+	return &Monitor{
+		conn: conn,
+		fd:   conn.Fd(),
+		buf:  make([]byte, maxMessageSize),
+	}, nil
+}
+
+func (m *Monitor) Close() error {
+	return m.conn.Close()
+}
+
+func (m *Monitor) Fd() int {
+	return m.fd
+}
+
+// Read returns the next event. Exactly one message is taken off the
+// socket so a level triggered poll keeps reporting the rest.
+func (m *Monitor) Read() (event.Event, error) {
+	n, _, err := syscall.Recvfrom(m.fd, m.buf, syscall.MSG_PEEK)
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, io.EOF
+	}
+
+	end := bytes.IndexByte(m.buf[:n], '\n')
+	if end == -1 {
+		if n == len(m.buf) {
+			return nil, fmt.Errorf("message too large")
+		}
+		return nil, syscall.EAGAIN
+	}
+
+	if _, err := io.ReadFull(fdReader(m.fd), m.buf[:end+1]); err != nil {
+		return nil, err
+	}
+
+	var message struct {
+		Type    string          `json:"type"`
+		Payload json.RawMessage `json:"payload"`
+	}
+	if err := json.Unmarshal(m.buf[:end+1], &message); err != nil {
+		return nil, err
+	}
+	if message.Type != "event" {
+		return nil, fmt.Errorf("unexpected message %q", message.Type)
+	}
+
+	var e Event
+	if err := json.Unmarshal(message.Payload, &e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Settle waits until udevd processed every event the kernel has sent.
+func Settle(timeout time.Duration) error {
+	conn, err := connect.Connect(SocketID)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return err
+	}
+
+	var reply any
+	return conn.Send("settle", nil, &reply)
+}
+
+type fdReader int
+
+func (fd fdReader) Read(p []byte) (int, error) {
+	n, err := syscall.Read(int(fd), p)
+	if n < 0 {
+		n = 0
+	}
+	if err == nil && n == 0 && len(p) > 0 {
+		return 0, io.EOF
+	}
+	return n, err
+}
@@ -0,0 +1,55 @@
+/*
+ * Copyright (c) 2025 Manjeet Singh <itsmanjeet1998@gmail.com>.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 3.
+ *
+ * This program is distributed in the hope that it will be useful, but
+ * WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+ * General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+package udev
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ParseUevent parses a kernel uevent as received from the netlink socket,
+// ACTION@DEVPATH followed by NUL separated KEY=VALUE pairs.
+func ParseUevent(data []byte) Event {
+	e := Event{
+		Properties: map[string]string{},
+	}
+
+	for _, part := range strings.Split(string(data), "\u0000") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			continue
+		}
+
+		switch key {
+		case "ACTION":
+			e.Action = value
+		case "DEVPATH":
+			e.DevicePath = value
+		case "SUBSYSTEM":
+			e.Subsystem = value
+		case "DEVNAME":
+			e.DeviceName = value
+			e.DeviceNode = "/dev/" + value
+		default:
+			if key == "SEQNUM" {
+				e.Sequence, _ = strconv.ParseUint(value, 10, 64)
+			}
+			e.Properties[key] = value
+		}
+	}
+	return e
+}
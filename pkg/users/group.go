@@ -0,0 +1,77 @@
+/*
+ * Copyright (c) 2025 Manjeet Singh <itsmanjeet1998@gmail.com>.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 3.
+ *
+ * This program is distributed in the hope that it will be useful, but
+ * WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+ * General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+package users
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type Group struct {
+	Name     string
+	Password string
+	GID      int
+	Members  []string
+}
+
+func ReadGroup(path string) ([]Group, error) {
+	entries, err := readEntries(path, 4)
+	if err != nil {
+		return nil, err
+	}
+
+	var groups []Group
+	for _, fields := range entries {
+		gid, err := parseID(fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("%s: group %s: %v", path, fields[0], err)
+		}
+
+		var members []string
+		if fields[3] != "" {
+			members = strings.Split(fields[3], ",")
+		}
+
+		groups = append(groups, Group{
+			Name:     fields[0],
+			Password: fields[1],
+			GID:      gid,
+			Members:  members,
+		})
+	}
+	return groups, nil
+}
+
+func WriteGroup(path string, groups []Group) error {
+	var entries [][]string
+	for _, g := range groups {
+		entries = append(entries, []string{
+			g.Name, g.Password, strconv.Itoa(g.GID), strings.Join(g.Members, ","),
+		})
+	}
+	return writeEntries(path, entries, 0644)
+}
+
+func LookupGroup(groups []Group, name string) (Group, bool) {
+	for _, g := range groups {
+		if g.Name == name {
+			return g, true
+		}
+	}
+	return Group{}, false
+}
@@ -0,0 +1,83 @@
+/*
+ * Copyright (c) 2025 Manjeet Singh <itsmanjeet1998@gmail.com>.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 3.
+ *
+ * This program is distributed in the hope that it will be useful, but
+ * WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+ * General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+package users
+
+import (
+	"fmt"
+	"strconv"
+)
+
+type User struct {
+	Name     string
+	Password string
+	UID      int
+	GID      int
+	Comment  string
+	Home     string
+	Shell    string
+}
+
+func ReadPasswd(path string) ([]User, error) {
+	entries, err := readEntries(path, 7)
+	if err != nil {
+		return nil, err
+	}
+
+	var users []User
+	for _, fields := range entries {
+		uid, err := parseID(fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("%s: user %s: %v", path, fields[0], err)
+		}
+		gid, err := parseID(fields[3])
+		if err != nil {
+			return nil, fmt.Errorf("%s: user %s: %v", path, fields[0], err)
+		}
+
+		users = append(users, User{
+			Name:     fields[0],
+			Password: fields[1],
+			UID:      uid,
+			GID:      gid,
+			Comment:  fields[4],
+			Home:     fields[5],
+			Shell:    fields[6],
+		})
+	}
+	return users, nil
+}
+
+func WritePasswd(path string, users []User) error {
+	var entries [][]string
+	for _, u := range users {
+		entries = append(entries, []string{
+			u.Name, u.Password,
+			strconv.Itoa(u.UID), strconv.Itoa(u.GID),
+			u.Comment, u.Home, u.Shell,
+		})
+	}
+	return writeEntries(path, entries, 0644)
+}
+
+func LookupUser(users []User, name string) (User, bool) {
+	for _, u := range users {
+		if u.Name == name {
+			return u, true
+		}
+	}
+	return User{}, false
+}
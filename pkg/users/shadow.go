@@ -0,0 +1,65 @@
+/*
+ * Copyright (c) 2025 Manjeet Singh <itsmanjeet1998@gmail.com>.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 3.
+ *
+ * This program is distributed in the hope that it will be useful, but
+ * WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+ * General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+package users
+
+// Shadow keeps the numeric aging fields as strings, empty fields are
+// meaningful and have to survive a round trip.
+type Shadow struct {
+	Name       string
+	Hash       string
+	LastChange string
+	Min        string
+	Max        string
+	Warn       string
+	Inactive   string
+	Expire     string
+	Reserved   string
+}
+
+func ReadShadow(path string) ([]Shadow, error) {
+	entries, err := readEntries(path, 9)
+	if err != nil {
+		return nil, err
+	}
+
+	var shadows []Shadow
+	for _, f := range entries {
+		shadows = append(shadows, Shadow{
+			Name:       f[0],
+			Hash:       f[1],
+			LastChange: f[2],
+			Min:        f[3],
+			Max:        f[4],
+			Warn:       f[5],
+			Inactive:   f[6],
+			Expire:     f[7],
+			Reserved:   f[8],
+		})
+	}
+	return shadows, nil
+}
+
+func WriteShadow(path string, shadows []Shadow) error {
+	var entries [][]string
+	for _, s := range shadows {
+		entries = append(entries, []string{
+			s.Name, s.Hash, s.LastChange, s.Min, s.Max,
+			s.Warn, s.Inactive, s.Expire, s.Reserved,
+		})
+	}
+	return writeEntries(path, entries, 0600)
+}
@@ -0,0 +1,149 @@
+/*
+ * Copyright (c) 2025 Manjeet Singh <itsmanjeet1998@gmail.com>.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 3.
+ *
+ * This program is distributed in the hope that it will be useful, but
+ * WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+ * General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+package users
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+const (
+	PasswdPath = "/etc/passwd"
+	GroupPath  = "/etc/group"
+	ShadowPath = "/etc/shadow"
+
+	SystemMinID = 100
+	SystemMaxID = 999
+	UserMinID   = 1000
+	UserMaxID   = 60000
+)
+
+// readEntries returns the colon separated fields of every non empty,
+// non comment line of path.
+func readEntries(path string, count int) ([][]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries [][]string
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Split(text, ":")
+		if len(fields) != count {
+			return nil, fmt.Errorf("%s:%d: expected %d fields, got %d", path, line, count, len(fields))
+		}
+		entries = append(entries, fields)
+	}
+	return entries, scanner.Err()
+}
+
+// writeEntries atomically replaces path with entries. Comments and blank
+// lines stay where they were, entries already in the file are updated in
+// place and new ones are appended. Permissions and owner are kept.
+func writeEntries(path string, entries [][]string, perm os.FileMode) error {
+	uid, gid := -1, -1
+	if info, err := os.Stat(path); err == nil {
+		perm = info.Mode().Perm()
+		if st, ok := info.Sys().(*syscall.Stat_t); ok {
+			uid, gid = int(st.Uid), int(st.Gid)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	pending := map[string][]string{}
+	for _, fields := range entries {
+		pending[fields[0]] = fields
+	}
+
+	var sb strings.Builder
+	for _, text := range strings.SplitAfter(string(data), "\n") {
+		line := strings.TrimSuffix(text, "\n")
+		if line == "" || strings.HasPrefix(line, "#") {
+			if text != "" {
+				sb.WriteString(line)
+				sb.WriteByte('\n')
+			}
+			continue
+		}
+
+		// entries that are gone are dropped
+		name, _, _ := strings.Cut(line, ":")
+		if fields, ok := pending[name]; ok {
+			sb.WriteString(strings.Join(fields, ":"))
+			sb.WriteByte('\n')
+			delete(pending, name)
+		}
+	}
+
+	for _, fields := range entries {
+		if _, ok := pending[fields[0]]; ok {
+			sb.WriteString(strings.Join(fields, ":"))
+			sb.WriteByte('\n')
+			delete(pending, fields[0])
+		}
+	}
+
+	tmp := filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".new")
+	if err := os.WriteFile(tmp, []byte(sb.String()), perm); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp, perm); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	if uid != -1 {
+		if err := os.Chown(tmp, uid, gid); err != nil {
+			_ = os.Remove(tmp)
+			return err
+		}
+	}
+	return os.Rename(tmp, path)
+}
+
+func parseID(s string) (int, error) {
+	id, err := strconv.Atoi(s)
+	if err != nil || id < 0 {
+		return 0, fmt.Errorf("invalid id %q", s)
+	}
+	return id, nil
+}
+
+// FreeID returns the lowest id in [min, max] not in used.
+func FreeID(used map[int]bool, min, max int) (int, error) {
+	for id := min; id <= max; id++ {
+		if !used[id] {
+			return id, nil
+		}
+	}
+	return 0, fmt.Errorf("no free id between %d and %d", min, max)
+}
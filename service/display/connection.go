@@ -18,39 +18,72 @@
 package main
 
 import (
-	"encoding/json"
+	"errors"
+	"io"
+	"log"
 
 	"chillos/pkg/connect"
 	"chillos/pkg/event"
+	"chillos/service/display/protocol"
 	"chillos/service/display/surface"
 )
 
 type Connection struct {
 	*connect.Connection
+	conn *protocol.Conn
+}
+
+func NewConnection(c *connect.Connection) *Connection {
+	return &Connection{
+		Connection: c,
+		conn:       protocol.NewConn(c),
+	}
+}
+
+// Finish closes the connection once the client went away, its surfaces
+// and their buffers are released by the desktop.
+func (c *Connection) Finish() event.Event {
+	_ = c.Close()
+	return surface.Disconnect{Conn: c.conn}
 }
 
 func (c *Connection) Read() (event.Event, error) {
-	cmd, buf, err := c.Receive()
+	msg, err := c.conn.Read()
+	if errors.Is(err, protocol.ErrBroken) {
+		// the stream cannot be trusted anymore
+		return nil, io.EOF
+	}
 	if err != nil {
+		if msg.Kind == protocol.Request {
+			code := protocol.ErrorInvalid
+			switch {
+			case errors.Is(err, protocol.ErrVersion):
+				code = protocol.ErrorVersion
+			case errors.Is(err, protocol.ErrUnknownType):
+				code = protocol.ErrorUnknownOpcode
+			}
+			_ = c.conn.ReplyError(msg.Serial, code, "%v", err)
+		}
 		return nil, err
 	}
 
-	switch cmd {
-	case "surface.Create":
-		var e surface.Create
-		if err := json.Unmarshal(buf, &e); err != nil {
-			return nil, err
-		}
-		e.Conn = c.Connection
-		return e, nil
+	switch body := msg.Body.(type) {
+	case protocol.CreateSurface:
+		return surface.Create{CreateSurface: body, Conn: c.conn, Serial: msg.Serial}, nil
 
-	case "surface.Damage":
-		var d surface.Damage
-		if err := json.Unmarshal(buf, &d); err != nil {
-			return nil, err
-		}
-		d.Conn = c.Connection
-		return d, nil
+	case protocol.Damage:
+		return surface.Damage{Damage: body, Conn: c.conn}, nil
+
+	case protocol.DestroySurface:
+		return surface.Destroy{DestroySurface: body, Conn: c.conn, Serial: msg.Serial}, nil
+
+	case protocol.Frame:
+		return surface.Frame{Frame: body, Conn: c.conn}, nil
+	}
+
+	log.Printf("unexpected %v opcode %d from client", msg.Kind, msg.Opcode)
+	if msg.Kind == protocol.Request {
+		_ = c.conn.ReplyError(msg.Serial, protocol.ErrorUnknownOpcode, "unexpected opcode %d", msg.Opcode)
 	}
 	return nil, nil
 }
@@ -26,6 +26,7 @@ import (
 	"chillos/pkg/event/key"
 	"chillos/pkg/graphics"
 	"chillos/pkg/graphics/canvas"
+	"chillos/pkg/graphics/region"
 	"chillos/pkg/graphics/style"
 	"chillos/pkg/graphics/widget"
 	"chillos/service/display/screen"
@@ -59,11 +60,21 @@ func (d *Display) SetBounds(rect image.Rectangle) {
 	d.screen.SetBounds(rect)
 }
 
+// Draw paints the background only when everything is repainted, the
+// screen paints over what it damaged itself.
 func (d *Display) Draw(cv canvas.Canvas) {
-	graphics.FillRect(cv, d.Bounds(), 0, d.background)
+	if d.Base.Dirty() {
+		graphics.FillRect(cv, d.Bounds(), 0, d.background)
+	}
 	d.screen.Draw(cv)
 }
 
+func (d *Display) Damage() region.Region {
+	damage := d.Base.Damage()
+	damage.Union(widget.DamageOf(d.screen))
+	return damage
+}
+
 func (d *Display) Update(ev event.Event) bool {
 	switch ev := ev.(type) {
 	case cursor.Event:
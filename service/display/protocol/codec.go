@@ -0,0 +1,164 @@
+/*
+ * Copyright (c) 2025 Manjeet Singh <itsmanjeet1998@gmail.com>.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 3.
+ *
+ * This program is distributed in the hope that it will be useful, but
+ * WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+ * General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package protocol
+
+import (
+	"encoding/binary"
+	"image"
+)
+
+// writer appends little endian fields to a payload.
+type writer struct {
+	buf []byte
+}
+
+func (w *writer) uint8(v uint8) {
+	w.buf = append(w.buf, v)
+}
+
+func (w *writer) bool(v bool) {
+	if v {
+		w.uint8(1)
+	} else {
+		w.uint8(0)
+	}
+}
+
+func (w *writer) uint32(v uint32) {
+	w.buf = binary.LittleEndian.AppendUint32(w.buf, v)
+}
+
+func (w *writer) int32(v int) {
+	w.uint32(uint32(int32(v)))
+}
+
+func (w *writer) rect(r image.Rectangle) {
+	w.int32(r.Min.X)
+	w.int32(r.Min.Y)
+	w.int32(r.Max.X)
+	w.int32(r.Max.Y)
+}
+
+func (w *writer) rects(rects []image.Rectangle) {
+	w.uint32(uint32(len(rects)))
+	for _, r := range rects {
+		w.rect(r)
+	}
+}
+
+func (w *writer) string(s string) {
+	w.uint32(uint32(len(s)))
+	w.buf = append(w.buf, s...)
+}
+
+// reader consumes little endian fields from a payload, reading past the
+// end sets err and yields zero values.
+type reader struct {
+	buf []byte
+	err error
+}
+
+func (r *reader) take(n int) []byte {
+	if r.err != nil || n < 0 || len(r.buf) < n {
+		r.err = ErrShort
+		return nil
+	}
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b
+}
+
+func (r *reader) uint8() uint8 {
+	if b := r.take(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *reader) bool() bool {
+	return r.uint8() != 0
+}
+
+func (r *reader) uint32() uint32 {
+	if b := r.take(4); b != nil {
+		return binary.LittleEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (r *reader) int32() int {
+	return int(int32(r.uint32()))
+}
+
+func (r *reader) rect() image.Rectangle {
+	var rect image.Rectangle
+	rect.Min.X = r.int32()
+	rect.Min.Y = r.int32()
+	rect.Max.X = r.int32()
+	rect.Max.Y = r.int32()
+	return rect
+}
+
+func (r *reader) rects() []image.Rectangle {
+	n := r.uint32()
+	if uint64(n)*16 > uint64(len(r.buf)) {
+		r.err = ErrShort
+		return nil
+	}
+	rects := make([]image.Rectangle, n)
+	for i := range rects {
+		rects[i] = r.rect()
+	}
+	return rects
+}
+
+// more reports whether fields appended by a newer peer follow.
+func (r *reader) more() bool {
+	return r.err == nil && len(r.buf) != 0
+}
+
+func (r *reader) string() string {
+	n := r.uint32()
+	if n > MaxPayloadSize {
+		r.err = ErrShort
+		return ""
+	}
+	return string(r.take(int(n)))
+}
+
+func (h Header) encode(buf []byte) {
+	binary.LittleEndian.PutUint32(buf[0:], h.Size)
+	binary.LittleEndian.PutUint16(buf[4:], h.Version)
+	binary.LittleEndian.PutUint16(buf[6:], uint16(h.Opcode))
+	buf[8] = uint8(h.Kind)
+	buf[9] = h.Flags
+	binary.LittleEndian.PutUint16(buf[10:], h.Fds)
+	binary.LittleEndian.PutUint32(buf[12:], h.Serial)
+}
+
+func decodeHeader(buf []byte) Header {
+	return Header{
+		Size:    binary.LittleEndian.Uint32(buf[0:]),
+		Version: binary.LittleEndian.Uint16(buf[4:]),
+		Opcode:  Opcode(binary.LittleEndian.Uint16(buf[6:])),
+		Kind:    Kind(buf[8]),
+		Flags:   buf[9],
+		Fds:     binary.LittleEndian.Uint16(buf[10:]),
+		Serial:  binary.LittleEndian.Uint32(buf[12:]),
+	}
+}
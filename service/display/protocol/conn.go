@@ -0,0 +1,273 @@
+/*
+ * Copyright (c) 2025 Manjeet Singh <itsmanjeet1998@gmail.com>.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 3.
+ *
+ * This program is distributed in the hope that it will be useful, but
+ * WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+ * General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package protocol
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"syscall"
+
+	"chillos/pkg/event"
+)
+
+const (
+	// DebugEnv selects JSON payloads when set to json
+	DebugEnv = "DISPLAY_PROTOCOL"
+)
+
+// Stream is what messages travel on, a unix socket connection able to
+// pass descriptors.
+type Stream interface {
+	ReadFds(p []byte) (int, []int, error)
+	WriteFds(p []byte, fds []int) (int, error)
+}
+
+// Message is a decoded message, Body is nil when it could not be decoded.
+type Message struct {
+	Header
+	Body event.Event
+}
+
+// Conn reads and writes messages on a stream. Reads take exactly one
+// message from the stream so the peer can be polled on its descriptor,
+// writes may come from any goroutine.
+type Conn struct {
+	rw     Stream
+	json   atomic.Bool
+	serial atomic.Uint32
+	wmutex sync.Mutex
+
+	// messages read while waiting for a reply
+	queue []Message
+}
+
+func NewConn(rw Stream) *Conn {
+	c := &Conn{rw: rw}
+	c.json.Store(os.Getenv(DebugEnv) == "json")
+	return c
+}
+
+// Request sends a request and returns its serial.
+func (c *Conn) Request(m event.Event) (uint32, error) {
+	serial := c.serial.Add(1)
+	return serial, c.write(Request, serial, m)
+}
+
+// Call sends a request and waits for its reply, an error reply is
+// returned as an ErrorReply error. Anything else read meanwhile is kept
+// for Read.
+func (c *Conn) Call(m event.Event) (event.Event, error) {
+	serial, err := c.Request(m)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		msg, err := c.readMessage()
+		if err != nil {
+			return nil, err
+		}
+		if msg.Serial != serial || (msg.Kind != Reply && msg.Kind != Error) {
+			c.queue = append(c.queue, msg)
+			continue
+		}
+		if e, ok := msg.Body.(ErrorReply); ok {
+			return nil, e
+		}
+		return msg.Body, nil
+	}
+}
+
+func (c *Conn) Reply(serial uint32, m event.Event) error {
+	return c.write(Reply, serial, m)
+}
+
+func (c *Conn) ReplyError(serial uint32, code uint32, format string, args ...any) error {
+	return c.write(Error, serial, ErrorReply{
+		Code:    code,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// Send sends an event.
+func (c *Conn) Send(m event.Event) error {
+	return c.write(Event, 0, m)
+}
+
+// Buffered returns the number of messages Read returns without touching
+// the stream.
+func (c *Conn) Buffered() int {
+	return len(c.queue)
+}
+
+// Read returns the next message. Messages with an unsupported version or
+// type are consumed and returned with their header and the error, so a
+// request can still be answered.
+func (c *Conn) Read() (Message, error) {
+	if len(c.queue) != 0 {
+		msg := c.queue[0]
+		c.queue = c.queue[1:]
+		return msg, nil
+	}
+	return c.readMessage()
+}
+
+func (c *Conn) readMessage() (Message, error) {
+	var fds []int
+	buf := make([]byte, HeaderSize)
+	if err := c.readFull(buf, &fds); err != nil {
+		closeFds(fds)
+		return Message{}, broken(err)
+	}
+
+	msg := Message{Header: decodeHeader(buf)}
+	if msg.Size > MaxPayloadSize {
+		closeFds(fds)
+		return msg, broken(ErrTooLarge)
+	}
+
+	payload := make([]byte, msg.Size)
+	if err := c.readFull(payload, &fds); err != nil {
+		closeFds(fds)
+		return msg, broken(err)
+	}
+
+	body, err := c.decode(msg.Header, payload, fds)
+	if err != nil {
+		closeFds(fds)
+		return msg, err
+	}
+	msg.Body = body
+	return msg, nil
+}
+
+func (c *Conn) decode(h Header, payload []byte, fds []int) (event.Event, error) {
+	if h.Version != Version {
+		return nil, fmt.Errorf("%w %d", ErrVersion, h.Version)
+	}
+
+	// answer in the encoding the peer speaks
+	c.json.Store(h.Flags&FlagJSON != 0)
+
+	ptr, ok := newMessage(h.Opcode)
+	if !ok {
+		return nil, fmt.Errorf("%w %d", ErrUnknownType, h.Opcode)
+	}
+
+	if h.Flags&FlagJSON != 0 {
+		if err := json.Unmarshal(payload, ptr); err != nil {
+			return nil, err
+		}
+	} else {
+		r := reader{buf: payload}
+		decodeMessage(&r, ptr)
+		if r.err != nil {
+			return nil, fmt.Errorf("opcode %d: %w", h.Opcode, r.err)
+		}
+	}
+
+	if int(h.Fds) != len(fds) || !setFds(ptr, fds) {
+		return nil, fmt.Errorf("opcode %d with %d descriptors: %w", h.Opcode, len(fds), ErrFds)
+	}
+
+	body := reflect.ValueOf(ptr).Elem().Interface().(event.Event)
+	if _, kind, _ := kindOf(body); kind != h.Kind {
+		return nil, fmt.Errorf("opcode %d is not a %v", h.Opcode, h.Kind)
+	}
+	return body, nil
+}
+
+// readFull fills buf, collecting the descriptors that come along.
+func (c *Conn) readFull(buf []byte, fds *[]int) error {
+	for read := 0; read < len(buf); {
+		n, received, err := c.rw.ReadFds(buf[read:])
+		*fds = append(*fds, received...)
+		read += n
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			// a message cut short is a closed connection all the same
+			return io.EOF
+		}
+	}
+	return nil
+}
+
+// broken marks errors after which the stream can not be read any further,
+// io.EOF stays as it is.
+func broken(err error) error {
+	if err == io.EOF {
+		return err
+	}
+	return fmt.Errorf("%w: %w", ErrBroken, err)
+}
+
+func closeFds(fds []int) {
+	for _, fd := range fds {
+		_ = syscall.Close(fd)
+	}
+}
+
+func (c *Conn) write(kind Kind, serial uint32, m event.Event) error {
+	op, k, ok := kindOf(m)
+	if !ok {
+		return fmt.Errorf("%T: %w", m, ErrUnknownType)
+	}
+	if k != kind {
+		return fmt.Errorf("%T is not a %v", m, kind)
+	}
+
+	h := Header{
+		Version: Version,
+		Opcode:  op,
+		Kind:    kind,
+		Serial:  serial,
+	}
+
+	w := writer{buf: make([]byte, HeaderSize, HeaderSize+64)}
+	if c.json.Load() {
+		h.Flags |= FlagJSON
+		payload, err := json.Marshal(m)
+		if err != nil {
+			return err
+		}
+		w.buf = append(w.buf, payload...)
+	} else {
+		encodeMessage(&w, m)
+	}
+
+	if len(w.buf)-HeaderSize > MaxPayloadSize {
+		return ErrTooLarge
+	}
+	fds := fdsOf(m)
+	h.Size = uint32(len(w.buf) - HeaderSize)
+	h.Fds = uint16(len(fds))
+	h.encode(w.buf)
+
+	c.wmutex.Lock()
+	defer c.wmutex.Unlock()
+
+	_, err := c.rw.WriteFds(w.buf, fds)
+	return err
+}
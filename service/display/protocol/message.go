@@ -0,0 +1,281 @@
+/*
+ * Copyright (c) 2025 Manjeet Singh <itsmanjeet1998@gmail.com>.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 3.
+ *
+ * This program is distributed in the hope that it will be useful, but
+ * WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+ * General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package protocol
+
+import (
+	"image"
+
+	"chillos/pkg/event"
+	"chillos/pkg/event/button"
+	"chillos/pkg/event/cursor"
+	"chillos/pkg/event/key"
+)
+
+// CreateSurface asks for a surface of the size of Rect, answered by
+// SurfaceCreated.
+type CreateSurface struct {
+	Rect image.Rectangle
+}
+
+func (m CreateSurface) Event() {}
+
+// SurfaceCreated carries the buffer of the new surface, owned by the
+// server and mapped by the client.
+type SurfaceCreated struct {
+	Id     int
+	Rect   image.Rectangle
+	Buffer int `json:"-"`
+}
+
+func (m SurfaceCreated) Event() {}
+
+// DestroySurface releases the surface Id, the client is done with it.
+type DestroySurface struct {
+	Id int
+}
+
+func (m DestroySurface) Event() {}
+
+// Damage tells the surface Id has new content in Rect, in surface
+// coordinates. Rects, when sent, narrows it down to those rectangles.
+type Damage struct {
+	Id    int
+	Rect  image.Rectangle
+	Rects []image.Rectangle
+}
+
+func (m Damage) Event() {}
+
+// Frame asks for a FrameDone once it is a good time to draw the next
+// frame of surface Id. It is sent before the Damage of the current frame,
+// surfaces that are not shown get none until they are shown again.
+type Frame struct {
+	Id int
+}
+
+func (m Frame) Event() {}
+
+// FrameDone tells the client the display presented a frame and surface
+// Id may be drawn again.
+type FrameDone struct {
+	Id int
+}
+
+func (m FrameDone) Event() {}
+
+// SurfaceDestroyed tells the client the display removed surface Id, its
+// buffer is gone and requests naming it are ignored.
+type SurfaceDestroyed struct {
+	Id int
+}
+
+func (m SurfaceDestroyed) Event() {}
+
+// Resize tells the client its surface was reallocated with a new size
+// and a new buffer.
+type Resize struct {
+	Id     int
+	Rect   image.Rectangle
+	Buffer int `json:"-"`
+}
+
+func (m Resize) Event() {}
+
+// ErrorReply is the answer to a request that failed.
+type ErrorReply struct {
+	Code    uint32
+	Message string
+}
+
+func (m ErrorReply) Event() {}
+
+func (m ErrorReply) Error() string {
+	return m.Message
+}
+
+// kindOf returns the opcode and kind of a message, key, button and cursor
+// events travel as they are.
+func kindOf(m event.Event) (Opcode, Kind, bool) {
+	switch m.(type) {
+	case CreateSurface:
+		return OpCreateSurface, Request, true
+	case Damage:
+		return OpDamage, Request, true
+	case DestroySurface:
+		return OpDestroySurface, Request, true
+	case Frame:
+		return OpFrame, Request, true
+	case ErrorReply:
+		return OpError, Error, true
+	case SurfaceCreated:
+		return OpSurfaceCreated, Reply, true
+	case Resize:
+		return OpResize, Event, true
+	case FrameDone:
+		return OpFrameDone, Event, true
+	case SurfaceDestroyed:
+		return OpSurfaceDestroyed, Event, true
+	case key.Event:
+		return OpKey, Event, true
+	case button.Event:
+		return OpButton, Event, true
+	case cursor.Event:
+		return OpCursor, Event, true
+	}
+	return 0, 0, false
+}
+
+// fdsOf returns the descriptors sent along with m.
+func fdsOf(m event.Event) []int {
+	switch m := m.(type) {
+	case SurfaceCreated:
+		return []int{m.Buffer}
+	case Resize:
+		return []int{m.Buffer}
+	}
+	return nil
+}
+
+// setFds hands the received descriptors to m, it takes as many as it
+// carries.
+func setFds(m any, fds []int) bool {
+	switch m := m.(type) {
+	case *SurfaceCreated:
+		if len(fds) != 1 {
+			return false
+		}
+		m.Buffer = fds[0]
+	case *Resize:
+		if len(fds) != 1 {
+			return false
+		}
+		m.Buffer = fds[0]
+	default:
+		return len(fds) == 0
+	}
+	return true
+}
+
+// newMessage returns a pointer to the zero message of op.
+func newMessage(op Opcode) (any, bool) {
+	switch op {
+	case OpCreateSurface:
+		return &CreateSurface{}, true
+	case OpDamage:
+		return &Damage{}, true
+	case OpDestroySurface:
+		return &DestroySurface{}, true
+	case OpFrame:
+		return &Frame{}, true
+	case OpFrameDone:
+		return &FrameDone{}, true
+	case OpSurfaceDestroyed:
+		return &SurfaceDestroyed{}, true
+	case OpError:
+		return &ErrorReply{}, true
+	case OpSurfaceCreated:
+		return &SurfaceCreated{}, true
+	case OpResize:
+		return &Resize{}, true
+	case OpKey:
+		return &key.Event{}, true
+	case OpButton:
+		return &button.Event{}, true
+	case OpCursor:
+		return &cursor.Event{}, true
+	}
+	return nil, false
+}
+
+func encodeMessage(w *writer, m event.Event) {
+	switch m := m.(type) {
+	case CreateSurface:
+		w.rect(m.Rect)
+	case Damage:
+		w.int32(m.Id)
+		w.rect(m.Rect)
+		w.rects(m.Rects)
+	case DestroySurface:
+		w.int32(m.Id)
+	case Frame:
+		w.int32(m.Id)
+	case FrameDone:
+		w.int32(m.Id)
+	case SurfaceDestroyed:
+		w.int32(m.Id)
+	case ErrorReply:
+		w.uint32(m.Code)
+		w.string(m.Message)
+	case SurfaceCreated:
+		w.int32(m.Id)
+		w.rect(m.Rect)
+	case Resize:
+		w.int32(m.Id)
+		w.rect(m.Rect)
+	case key.Event:
+		w.int32(m.Key)
+		w.uint8(uint8(m.State))
+	case button.Event:
+		w.uint8(uint8(m.Button))
+		w.uint8(uint8(m.State))
+	case cursor.Event:
+		w.int32(m.Pos.X)
+		w.int32(m.Pos.Y)
+		w.bool(m.Abs)
+	}
+}
+
+func decodeMessage(r *reader, m any) {
+	switch m := m.(type) {
+	case *CreateSurface:
+		m.Rect = r.rect()
+	case *Damage:
+		m.Id = r.int32()
+		m.Rect = r.rect()
+		if r.more() {
+			m.Rects = r.rects()
+		}
+	case *DestroySurface:
+		m.Id = r.int32()
+	case *Frame:
+		m.Id = r.int32()
+	case *FrameDone:
+		m.Id = r.int32()
+	case *SurfaceDestroyed:
+		m.Id = r.int32()
+	case *ErrorReply:
+		m.Code = r.uint32()
+		m.Message = r.string()
+	case *SurfaceCreated:
+		m.Id = r.int32()
+		m.Rect = r.rect()
+	case *Resize:
+		m.Id = r.int32()
+		m.Rect = r.rect()
+	case *key.Event:
+		m.Key = r.int32()
+		m.State = key.State(r.uint8())
+	case *button.Event:
+		m.Button = button.Button(r.uint8())
+		m.State = button.State(r.uint8())
+	case *cursor.Event:
+		m.Pos.X = r.int32()
+		m.Pos.Y = r.int32()
+		m.Abs = r.bool()
+	}
+}
@@ -0,0 +1,132 @@
+/*
+ * Copyright (c) 2025 Manjeet Singh <itsmanjeet1998@gmail.com>.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 3.
+ *
+ * This program is distributed in the hope that it will be useful, but
+ * WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+ * General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package protocol is the wire protocol between the display service and
+// its clients.
+//
+// Every message is a 16 byte little endian header followed by its payload:
+//
+//	size    uint32 payload length
+//	version uint16 protocol version
+//	opcode  uint16 message type
+//	kind    uint8  request, reply, event or error
+//	flags   uint8  FlagJSON when the payload is JSON
+//	fds     uint16 descriptors passed along as SCM_RIGHTS
+//	serial  uint32 request id, echoed by its reply or error
+//
+// Descriptors are not part of the payload, messages carrying buffers get
+// them in order from the ones passed with the header.
+//
+// Payloads are fixed layout binary, new fields are only ever appended so
+// decoders ignore what they do not know. Setting DISPLAY_PROTOCOL=json in
+// the client environment switches payloads to JSON for debugging, the
+// server answers every client in the encoding it uses.
+package protocol
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	Version = 1
+
+	HeaderSize = 16
+
+	// larger messages are a broken or hostile peer
+	MaxPayloadSize = 1 << 20
+
+	FlagJSON = 1 << 0
+)
+
+type Kind uint8
+
+const (
+	Request Kind = iota + 1
+	Reply
+	Event
+	Error
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Request:
+		return "request"
+	case Reply:
+		return "reply"
+	case Event:
+		return "event"
+	case Error:
+		return "error"
+	}
+	return fmt.Sprintf("kind(%d)", uint8(k))
+}
+
+type Opcode uint16
+
+const (
+	// requests
+	OpCreateSurface Opcode = iota + 1
+	OpDamage
+
+	// replies
+	OpError
+	OpSurfaceCreated
+
+	// events
+	OpResize
+	OpKey
+	OpButton
+	OpCursor
+
+	// requests added since
+	OpDestroySurface
+	OpFrame
+
+	// events added since
+	OpFrameDone
+	OpSurfaceDestroyed
+)
+
+type Header struct {
+	Size    uint32
+	Version uint16
+	Opcode  Opcode
+	Kind    Kind
+	Flags   uint8
+	Fds     uint16
+	Serial  uint32
+}
+
+// Error codes carried by error replies
+const (
+	ErrorInvalid uint32 = iota + 1
+	ErrorUnknownOpcode
+	ErrorVersion
+	ErrorFailed
+)
+
+var (
+	ErrVersion     = errors.New("unsupported protocol version")
+	ErrTooLarge    = errors.New("message too large")
+	ErrShort       = errors.New("message too short")
+	ErrUnknownType = errors.New("unknown message type")
+	ErrFds         = errors.New("descriptors do not match the message")
+
+	// the stream failed and is out of sync, only closing it is left
+	ErrBroken = errors.New("connection broken")
+)
@@ -25,8 +25,10 @@ import (
 	"syscall"
 
 	"chillos/pkg/event"
+	"chillos/pkg/event/frame"
 	"chillos/pkg/event/key"
 	"chillos/pkg/graphics/widget"
+	"chillos/service/display/protocol"
 	"chillos/service/display/screen"
 	"chillos/service/display/surface"
 )
@@ -75,21 +77,46 @@ func (d *Desktop) Update(ev event.Event) bool {
 		s, err := surface.NewSurface(ev.Rect, ev.Conn)
 		if err != nil {
 			d.setStatus("failed to create surface: %v", err)
+			_ = ev.Conn.ReplyError(ev.Serial, protocol.ErrorFailed, "failed to create surface: %v", err)
 			return true
 		}
 
 		d.workspace.AddChild(s)
-		if err := ev.Conn.Send("surface.Created", surface.Created{
-			Id:   s.Image.Key(),
-			Rect: s.Bounds(),
-		}, nil); err != nil {
+		if err := ev.Conn.Reply(ev.Serial, protocol.SurfaceCreated{
+			Id:     s.Id,
+			Rect:   s.Bounds(),
+			Buffer: s.Image.Fd(),
+		}); err != nil {
 			d.setStatus("failed to send surface.Created: %v", err)
 			return true
 		}
 
+	case surface.Destroy:
+		s, ok := d.workspace.surfaceFromId(ev.Conn, ev.Id)
+		if !ok {
+			_ = ev.Conn.ReplyError(ev.Serial, protocol.ErrorInvalid, "no surface %d", ev.Id)
+			return true
+		}
+		d.workspace.RemoveChild(s)
+
+	case surface.Disconnect:
+		d.workspace.removeConn(ev.Conn)
+
+	case surface.Frame:
+		if s, ok := d.workspace.surfaceFromId(ev.Conn, ev.Id); ok {
+			s.RequestFrame()
+		}
+
+	case frame.Event:
+		d.workspace.frameDone()
+
 	case surface.Damage:
-		if s, ok := d.workspace.surfaceFromConn(ev.Conn); ok {
-			s.SetDirty(true)
+		if s, ok := d.workspace.surfaceFromId(ev.Conn, ev.Id); ok {
+			if len(ev.Rects) != 0 {
+				s.DamageBuffer(ev.Rects...)
+			} else {
+				s.DamageBuffer(ev.Rect)
+			}
 		} else {
 			d.setStatus("no surface found for damage, damaging all")
 			d.SetDirty(true)
@@ -100,7 +127,7 @@ func (d *Desktop) Update(ev event.Event) bool {
 
 	case key.Event:
 		if !d.handleBindings(ev) {
-			if err := d.workspace.propagate("key-event", ev); err != nil {
+			if err := d.workspace.propagate(ev); err != nil {
 				d.setStatus("failed to propogate key event: %v", err)
 			}
 		}
@@ -123,8 +150,16 @@ func (d *Desktop) handleBindings(ev key.Event) bool {
 			d.setStatus("Window %v", idx)
 			d.workspace.Raise(idx)
 		case key.KEY_Q:
-			d.workspace.RemoveChild(d.workspace.activeSurface)
-			d.workspace.SetDirty(true)
+			s := d.workspace.activeSurface
+			if s == nil {
+				return true
+			}
+			d.workspace.RemoveChild(s)
+
+			// the client would keep drawing into a buffer that is gone
+			if err := s.Conn.Send(protocol.SurfaceDestroyed{Id: s.Id}); err != nil {
+				d.setStatus("failed to send surface.Destroyed: %v", err)
+			}
 
 		case key.KEY_R:
 			d.workspace.SetDirty(true)
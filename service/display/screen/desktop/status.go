@@ -53,13 +53,15 @@ func (s *Status) Dirty() bool {
 	if time.Since(s.tick) > time.Second {
 		s.tick = time.Now()
 		s.end.Text = s.tick.Format("3:04 PM Mon, 2 Jan")
-		s.SetDirty(true)
-		return true
+		s.end.SetDirty(true)
 	}
-	return false
+	return s.Base.Dirty()
 }
 
 func (s *Status) Draw(cv canvas.Canvas) {
+	if !s.Base.Dirty() {
+		return
+	}
 	graphics.FillRect(cv, s.Bounds(), 0, s.BackgroundColor)
 	s.Base.Draw(cv)
 	graphics.Rect(cv, s.Bounds(), 0, s.BorderColor)
@@ -6,11 +6,12 @@ import (
 	"slices"
 	"sync"
 
-	"chillos/pkg/connect"
+	"chillos/pkg/event"
 	"chillos/pkg/graphics"
 	"chillos/pkg/graphics/canvas"
 	"chillos/pkg/graphics/style"
 	"chillos/pkg/graphics/widget"
+	"chillos/service/display/protocol"
 	"chillos/service/display/surface"
 )
 
@@ -19,9 +20,13 @@ type Workspace struct {
 
 	activeSurface *surface.Surface
 
+	background          color.Color
 	activeBorderColor   color.Color
 	inactiveBorderColor color.Color
 
+	// set when all of the workspace is to be painted, not just surfaces
+	repaint bool
+
 	mutex sync.Mutex
 }
 
@@ -30,24 +35,25 @@ func (w *Workspace) Construct() {
 }
 
 func (w *Workspace) OnStyleChange(s style.Style) {
+	w.background = s.Background
 	w.activeBorderColor = style.Lighten(s.Surface, 0.4)
 	w.inactiveBorderColor = style.Lighten(s.Surface, 0.1)
 }
 
-func (w *Workspace) propagate(c string, payload any) error {
+func (w *Workspace) propagate(ev event.Event) error {
 	if w.activeSurface == nil {
 		if len(w.Children) == 0 {
 			return nil
 		}
 		return fmt.Errorf("no active surface")
 	}
-	if err := w.activeSurface.Conn.Send(c, payload, nil); err != nil {
+	if err := w.activeSurface.Conn.Send(ev); err != nil {
 		return err
 	}
 	return nil
 }
 
-func (w *Workspace) surfaceFromConn(conn *connect.Connection) (*surface.Surface, bool) {
+func (w *Workspace) surfaceFromConn(conn *protocol.Conn) (*surface.Surface, bool) {
 	idx := slices.IndexFunc(w.Children, func(w widget.Widget) bool {
 		return w.(*surface.Surface).Conn == conn
 	})
@@ -57,6 +63,35 @@ func (w *Workspace) surfaceFromConn(conn *connect.Connection) (*surface.Surface,
 	return w.Children[idx].(*surface.Surface), true
 }
 
+func (w *Workspace) surfaceFromId(conn *protocol.Conn, id int) (*surface.Surface, bool) {
+	idx := slices.IndexFunc(w.Children, func(w widget.Widget) bool {
+		s := w.(*surface.Surface)
+		return s.Conn == conn && s.Id == id
+	})
+	if idx == -1 {
+		return nil, false
+	}
+	return w.Children[idx].(*surface.Surface), true
+}
+
+// removeConn removes every surface of a closed connection.
+func (w *Workspace) removeConn(conn *protocol.Conn) {
+	for {
+		s, ok := w.surfaceFromConn(conn)
+		if !ok {
+			return
+		}
+		w.RemoveChild(s)
+	}
+}
+
+// frameDone fires the frame callbacks latched into the presented frame.
+func (w *Workspace) frameDone() {
+	for _, c := range w.Children {
+		c.(*surface.Surface).FrameDone()
+	}
+}
+
 func (w *Workspace) Raise(idx int) {
 	w.mutex.Lock()
 	defer w.mutex.Unlock()
@@ -85,22 +120,45 @@ func (w *Workspace) AddChild(c widget.Widget) {
 	w.Base.AddChild(c)
 }
 
+// RemoveChild removes a surface and destroys the display side of its
+// buffer. The remaining surfaces are laid out again and the focus moves
+// to the surface that took the place of the removed one.
 func (w *Workspace) RemoveChild(c widget.Widget) {
 	if c == nil {
 		return
 	}
 
+	idx := slices.Index(w.Children, c)
+	if idx == -1 {
+		return
+	}
+
 	w.Base.RemoveChild(c)
+	c.Destroy()
+
 	if w.activeSurface == c.(*surface.Surface) {
+		w.activeSurface = nil
 		if len(w.Children) > 0 {
-			w.activeSurface = w.Children[len(w.Children)-1].(*surface.Surface)
-		} else {
-			w.activeSurface = nil
+			w.activeSurface = w.Children[min(idx, len(w.Children)-1)].(*surface.Surface)
 		}
 	}
+
+	// the area the surface covered has to be painted over
+	w.SetDirty(true)
+}
+
+func (w *Workspace) SetDirty(d bool) {
+	w.Base.SetDirty(d)
+	w.repaint = d
 }
 
+// Draw paints the damaged parts of the surfaces over the background, the
+// rest of the screen is left as it was.
 func (w *Workspace) Draw(cv canvas.Canvas) {
+	if w.repaint {
+		graphics.FillRect(cv, w.Bounds(), 0, w.background)
+	}
+
 	for _, c := range w.Children {
 		borderColor := w.inactiveBorderColor
 		if c == w.activeSurface {
@@ -108,6 +166,13 @@ func (w *Workspace) Draw(cv canvas.Canvas) {
 		}
 
 		if c.Dirty() {
+			// hidden surfaces keep their callbacks until they are shown
+			if s := c.(*surface.Surface); s.Bounds().Overlaps(w.Bounds()) {
+				s.LatchFrame()
+			}
+			for _, r := range widget.DamageOf(c) {
+				graphics.FillRect(cv, r, 0, w.background)
+			}
 			c.Draw(cv)
 			graphics.Rect(cv, c.Bounds(), 0, borderColor)
 			c.SetDirty(false)
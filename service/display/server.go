@@ -38,7 +38,7 @@ func (s *Server) Handle(conn *connect.Connection) {
 
 	fd := conn.Fd()
 	log.Println("new connection from", fd)
-	if err := app.Backend().Listen(&Connection{conn}); err != nil {
+	if err := app.Backend().Listen(NewConnection(conn)); err != nil {
 		log.Printf("failed to listen: %v", err)
 	}
 }
@@ -18,45 +18,47 @@
 package surface
 
 import (
-	"image"
-
-	"chillos/pkg/connect"
-	"chillos/pkg/kernel/shm"
+	"chillos/service/display/protocol"
 )
 
+// Create is a client request for a new surface, answered by a
+// protocol.SurfaceCreated reply with Serial.
 type Create struct {
-	Rect image.Rectangle
-	Conn *connect.Connection
+	protocol.CreateSurface
+	Conn   *protocol.Conn
+	Serial uint32
 }
 
 func (e Create) Event() {}
 
-type Created struct {
-	Id   int
-	Rect image.Rectangle
+type Damage struct {
+	protocol.Damage
+	Conn *protocol.Conn
 }
 
-func (e Created) Event() {}
-
-func (e Created) Image() (*shm.Image, error) {
-	return shm.NewImageForKey(e.Id, e.Rect.Dx(), e.Rect.Dy())
-}
+func (e Damage) Event() {}
 
-type Damage struct {
-	Id   int
-	Rect image.Rectangle
-	Conn *connect.Connection
+// Destroy is a client request to remove one of its surfaces.
+type Destroy struct {
+	protocol.DestroySurface
+	Conn   *protocol.Conn
+	Serial uint32
 }
 
-func (e Damage) Event() {}
+func (e Destroy) Event() {}
 
-type Resize struct {
-	Id   int
-	Rect image.Rectangle
+// Frame is a client request for a frame callback on one of its surfaces.
+type Frame struct {
+	protocol.Frame
+	Conn *protocol.Conn
 }
 
-func (e Resize) Event() {}
+func (e Frame) Event() {}
 
-func (e Resize) Image() (*shm.Image, error) {
-	return shm.NewImageForKey(e.Id, e.Rect.Dx(), e.Rect.Dy())
+// Disconnect is sent when a client connection closed, the surfaces it
+// created are gone with it.
+type Disconnect struct {
+	Conn *protocol.Conn
 }
+
+func (e Disconnect) Event() {}
@@ -18,29 +18,46 @@
 package surface
 
 import (
+	"fmt"
 	"image"
 	"image/draw"
 	"log"
+	"sync/atomic"
 
-	"chillos/pkg/connect"
 	"chillos/pkg/graphics/canvas"
 	"chillos/pkg/graphics/widget"
-	"chillos/pkg/kernel/shm"
+	"chillos/pkg/kernel/memfd"
+	"chillos/service/display/protocol"
 )
 
+var (
+	lastId atomic.Int32
+)
+
+// Surface is a client window. Its buffer belongs to the display, the
+// client maps the descriptor it is sent and the memory goes away once
+// both sides let go of it.
 type Surface struct {
 	widget.Base
 
-	Image *shm.Image
-	Conn  *connect.Connection
+	Id    int
+	Image *memfd.Image
+	Conn  *protocol.Conn
+
+	// the client waits for a frame callback
+	frame bool
+	// the callback is due once the frame being drawn is presented
+	latched bool
 }
 
-func NewSurface(rect image.Rectangle, conn *connect.Connection) (*Surface, error) {
-	img, err := shm.NewImage(rect.Dx(), rect.Dy())
+func NewSurface(rect image.Rectangle, conn *protocol.Conn) (*Surface, error) {
+	id := int(lastId.Add(1))
+	img, err := memfd.NewImage(fmt.Sprintf("surface-%d", id), rect.Dx(), rect.Dy())
 	if err != nil {
 		return nil, err
 	}
 	s := &Surface{
+		Id:    id,
 		Image: img,
 		Conn:  conn,
 	}
@@ -59,27 +76,64 @@ func (s *Surface) SetBounds(rect image.Rectangle) {
 	}
 	s.SetDirty(true)
 
-	newImage, err := shm.NewImage(rect.Dx(), rect.Dy())
+	newImage, err := memfd.NewImage(fmt.Sprintf("surface-%d", s.Id), rect.Dx(), rect.Dy())
 	if err != nil {
-		log.Printf("shm.NewImage: %v", err)
+		log.Printf("memfd.NewImage: %v", err)
 		return
 	}
 
 	oldImage := s.Image
 	s.Image = newImage
 
-	ev := Resize{
-		Id:   s.Image.Key(),
-		Rect: s.Image.Bounds(),
+	ev := protocol.Resize{
+		Id:     s.Id,
+		Rect:   s.Image.Bounds(),
+		Buffer: s.Image.Fd(),
 	}
 	log.Println("Resizing", ev)
-	if err := s.Conn.Send("surface.Resize", ev, nil); err != nil {
+	if err := s.Conn.Send(ev); err != nil {
 		log.Printf("failed to send resize event %v", err)
 	}
 
 	_ = oldImage.Destroy()
 }
 
+// RequestFrame asks for a protocol.FrameDone once a frame was presented.
+func (s *Surface) RequestFrame() {
+	s.frame = true
+}
+
+// LatchFrame ties the requested frame callback to the frame being drawn,
+// callbacks asked for after it wait for the next one.
+func (s *Surface) LatchFrame() {
+	if s.frame {
+		s.frame = false
+		s.latched = true
+	}
+}
+
+// FrameDone sends the frame callback latched into the presented frame, if
+// any.
+func (s *Surface) FrameDone() {
+	if !s.latched {
+		return
+	}
+	s.latched = false
+	if err := s.Conn.Send(protocol.FrameDone{Id: s.Id}); err != nil {
+		log.Printf("failed to send frame done %v", err)
+	}
+}
+
+// DamageBuffer marks rects of the buffer as changed by the client.
+func (s *Surface) DamageBuffer(rects ...image.Rectangle) {
+	for _, r := range rects {
+		s.Base.AddDamage(r.Add(s.Bounds().Min))
+	}
+}
+
+// Draw copies the damaged parts of the buffer.
 func (s *Surface) Draw(cv canvas.Canvas) {
-	draw.Draw(cv, s.Bounds(), s.Image, image.Point{}, draw.Over)
+	for _, r := range s.Damage() {
+		draw.Draw(cv, r, s.Image, r.Min.Sub(s.Bounds().Min), draw.Over)
+	}
 }
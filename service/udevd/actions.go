@@ -0,0 +1,270 @@
+/*
+ * Copyright (c) 2025 Manjeet Singh <itsmanjeet1998@gmail.com>.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 3.
+ *
+ * This program is distributed in the hope that it will be useful, but
+ * WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+ * General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"chillos/pkg/connect"
+)
+
+const (
+	RUN_TIMEOUT     = 30 * time.Second
+	SERVICE_MANAGER = "service"
+)
+
+type deviceLinks struct {
+	node  string
+	links []string
+}
+
+var (
+	rules []Rule
+
+	// symlinks created for each device path, removed with the device
+	symlinks      = map[string]deviceLinks{}
+	symlinksMutex sync.Mutex
+)
+
+// applyRules runs every matching rule on the event in order, later rules
+// see the properties set by earlier ones.
+func applyRules(e *Event) {
+	var (
+		owner, group, mode string
+		links              = e.persistentLinks()
+		run                []string
+		services           []string
+	)
+
+	for i := range rules {
+		rule := &rules[i]
+		if !rule.Match(e) {
+			continue
+		}
+
+		for key, value := range rule.Environ {
+			e.Properties[key] = e.expand(value)
+		}
+		if rule.Owner != "" {
+			owner = rule.Owner
+		}
+		if rule.Group != "" {
+			group = rule.Group
+		}
+		if rule.Mode != "" {
+			mode = rule.Mode
+		}
+		for _, link := range rule.Symlinks {
+			links = append(links, e.expand(link))
+		}
+		for _, cmd := range rule.Run {
+			run = append(run, e.expand(cmd))
+		}
+		if rule.Service != "" {
+			services = append(services, e.expand(rule.Service))
+		}
+
+		if rule.Last {
+			break
+		}
+	}
+
+	switch e.Action {
+	case "add", "change", "bind", "move":
+		if node := e.DeviceNode(); node != "" {
+			if err := setPermissions(node, owner, group, mode); err != nil {
+				log.Printf("%s: %v", node, err)
+			}
+			updateSymlinks(e.DevicePath, node, links)
+			e.Symlinks = links
+		}
+	case "remove":
+		updateSymlinks(e.DevicePath, "", nil)
+	}
+
+	for _, cmd := range run {
+		if err := e.run(cmd); err != nil {
+			log.Printf("%s: run %q: %v", e.DevicePath, cmd, err)
+		}
+	}
+
+	for _, service := range services {
+		var err error
+		switch e.Action {
+		case "add":
+			err = controlService("start", service)
+		case "remove":
+			err = controlService("stop", service)
+		}
+		if err != nil {
+			log.Printf("%s: %s: %v", e.DevicePath, service, err)
+		}
+	}
+}
+
+func setPermissions(node, owner, group, mode string) error {
+	if mode != "" {
+		m, err := strconv.ParseUint(mode, 8, 32)
+		if err != nil {
+			return fmt.Errorf("invalid mode %q", mode)
+		}
+		if err := os.Chmod(node, os.FileMode(m).Perm()); err != nil {
+			return err
+		}
+	}
+
+	if owner == "" && group == "" {
+		return nil
+	}
+
+	uid, gid := -1, -1
+	if owner != "" {
+		usr, err := user.Lookup(owner)
+		if err != nil {
+			return err
+		}
+		uid, _ = strconv.Atoi(usr.Uid)
+	}
+	if group != "" {
+		grp, err := user.LookupGroup(group)
+		if err != nil {
+			return err
+		}
+		gid, _ = strconv.Atoi(grp.Gid)
+	}
+	return os.Chown(node, uid, gid)
+}
+
+// updateSymlinks points links at node, links the device had before and
+// no longer wants are removed. An empty node drops all of them.
+func updateSymlinks(devpath, node string, links []string) {
+	symlinksMutex.Lock()
+	defer symlinksMutex.Unlock()
+
+	old := symlinks[devpath]
+	for _, link := range old.links {
+		if node != "" && slices.Contains(links, link) {
+			continue
+		}
+		removeSymlink(link, old.node)
+	}
+
+	if node == "" || len(links) == 0 {
+		delete(symlinks, devpath)
+		return
+	}
+
+	for _, link := range links {
+		if err := createSymlink(link, node); err != nil {
+			log.Printf("failed to create symlink %s: %v", link, err)
+		}
+	}
+	symlinks[devpath] = deviceLinks{node: node, links: links}
+}
+
+func linkPath(link string) string {
+	if filepath.IsAbs(link) {
+		return link
+	}
+	return filepath.Join("/dev", link)
+}
+
+func createSymlink(link, node string) error {
+	path := linkPath(link)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	target, err := filepath.Rel(filepath.Dir(path), node)
+	if err != nil {
+		target = node
+	}
+	if current, err := os.Readlink(path); err == nil && current == target {
+		return nil
+	}
+
+	tmp := path + ".udev-tmp"
+	_ = os.Remove(tmp)
+	if err := os.Symlink(target, tmp); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// removeSymlink removes link unless another device claimed it meanwhile.
+func removeSymlink(link, node string) {
+	path := linkPath(link)
+	target, err := os.Readlink(path)
+	if err != nil {
+		return
+	}
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(path), target)
+	}
+	if target == node {
+		_ = os.Remove(path)
+	}
+}
+
+func (e *Event) run(cmd string) error {
+	args := strings.Fields(cmd)
+	if len(args) == 0 {
+		return nil
+	}
+
+	ctxt, cancel := context.WithTimeout(context.Background(), RUN_TIMEOUT)
+	defer cancel()
+
+	c := exec.CommandContext(ctxt, args[0], args[1:]...)
+	c.Env = append(os.Environ(), e.Environ()...)
+	output, err := c.CombinedOutput()
+	if len(output) > 0 {
+		log.Printf("%s: %s", args[0], strings.TrimSpace(string(output)))
+	}
+	return err
+}
+
+// controlService asks the service manager to start or stop a service.
+func controlService(cmd, name string) error {
+	conn, err := connect.Connect(SERVICE_MANAGER)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var reply struct {
+		Error string `json:"error"`
+	}
+	if err := conn.Send(cmd, map[string]string{"name": name}, &reply); err != nil {
+		return err
+	}
+	if reply.Error != "" {
+		return fmt.Errorf("%s", reply.Error)
+	}
+	return nil
+}
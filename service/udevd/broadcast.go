@@ -0,0 +1,159 @@
+/*
+ * Copyright (c) 2025 Manjeet Singh <itsmanjeet1998@gmail.com>.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 3.
+ *
+ * This program is distributed in the hope that it will be useful, but
+ * WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+ * General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"chillos/pkg/connect"
+	"chillos/pkg/udev"
+)
+
+const (
+	// a subscriber that can't take an event within this is dropped
+	BROADCAST_TIMEOUT = time.Second
+
+	// events the kernel dropped never arrive, don't wait for them forever
+	SETTLE_TIMEOUT  = 2 * time.Minute
+	SETTLE_INTERVAL = 10 * time.Millisecond
+
+	KERNEL_SEQNUM_PATH = "/sys/kernel/uevent_seqnum"
+)
+
+type subscriber struct {
+	conn       *connect.Connection
+	subsystems []string
+}
+
+type broadcaster struct {
+	mutex       sync.Mutex
+	subscribers []*subscriber
+}
+
+var (
+	events = &broadcaster{}
+)
+
+func listenSubscribers() {
+	if err := connect.Listen(udev.SocketID, events); err != nil {
+		log.Printf("failed to listen for subscribers: %v", err)
+	}
+}
+
+func (b *broadcaster) Handle(conn *connect.Connection) {
+	go func() {
+		cmd, payload, err := conn.Receive()
+		if err != nil {
+			_ = conn.Close()
+			return
+		}
+
+		switch cmd {
+		case "subscribe":
+			b.subscribe(conn, payload)
+		case "settle":
+			settle()
+			_ = conn.Send("settled", nil, nil)
+			_ = conn.Close()
+		default:
+			log.Printf("unknown command %q", cmd)
+			_ = conn.Close()
+		}
+	}()
+}
+
+func (b *broadcaster) subscribe(conn *connect.Connection, payload []byte) {
+	var subscription udev.Subscription
+	if err := json.Unmarshal(payload, &subscription); err != nil {
+		log.Printf("invalid subscription: %v", err)
+		_ = conn.Close()
+		return
+	}
+
+	s := &subscriber{conn: conn, subsystems: subscription.Subsystems}
+	b.mutex.Lock()
+	b.subscribers = append(b.subscribers, s)
+	b.mutex.Unlock()
+
+	// subscribers never talk again, a failed read means they left
+	_, _, _ = conn.Receive()
+	b.remove(s)
+}
+
+// settle returns once every event the kernel sent before the request
+// has been received and processed.
+func settle() {
+	target, err := kernelSeqnum()
+	if err != nil {
+		log.Printf("failed to read kernel seqnum: %v", err)
+	}
+
+	deadline := time.Now().Add(SETTLE_TIMEOUT)
+	for received.Load() < target && time.Now().Before(deadline) {
+		time.Sleep(SETTLE_INTERVAL)
+	}
+	eventQueue.WaitIdle()
+}
+
+// kernelSeqnum is the SEQNUM of the last uevent the kernel sent.
+func kernelSeqnum() (uint64, error) {
+	data, err := os.ReadFile(KERNEL_SEQNUM_PATH)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+}
+
+func (b *broadcaster) remove(s *subscriber) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if i := slices.Index(b.subscribers, s); i != -1 {
+		b.subscribers = slices.Delete(b.subscribers, i, i+1)
+		_ = s.conn.Close()
+	}
+}
+
+// publish sends the processed event to every subscriber interested in
+// its subsystem.
+func (b *broadcaster) publish(e *Event) {
+	b.mutex.Lock()
+	subscribers := slices.Clone(b.subscribers)
+	b.mutex.Unlock()
+
+	message := e.Message()
+	for _, s := range subscribers {
+		if len(s.subsystems) > 0 && !slices.Contains(s.subsystems, e.SubSystem) {
+			continue
+		}
+
+		_ = s.conn.SetWriteDeadline(time.Now().Add(BROADCAST_TIMEOUT))
+		err := s.conn.Send("event", message, nil)
+		_ = s.conn.SetWriteDeadline(time.Time{})
+		if err != nil {
+			log.Printf("dropping subscriber: %v", err)
+			b.remove(s)
+		}
+	}
+}
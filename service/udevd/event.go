@@ -18,7 +18,17 @@
 package main
 
 import (
+	"log"
+	"os"
+	"path/filepath"
 	"strings"
+
+	"chillos/pkg/pool/job"
+	"chillos/pkg/udev"
+)
+
+const (
+	SYSFS_PATH = "/sys"
 )
 
 type Event struct {
@@ -27,46 +37,181 @@ type Event struct {
 	SubSystem  string
 	DeviceName string
 	Properties map[string]string
+	Symlinks   []string
+	Seqnum     uint64
 }
 
 func parseEvent(source []byte, length int) Event {
-	parts := strings.Split(string(source[:length]), "\u0000")
-	e := Event{
-		Properties: map[string]string{},
+	u := udev.ParseUevent(source[:length])
+	return Event{
+		Action:     u.Action,
+		DevicePath: u.DevicePath,
+		SubSystem:  u.Subsystem,
+		DeviceName: u.DeviceName,
+		Properties: u.Properties,
+		Seqnum:     u.Sequence,
 	}
+}
 
-	for _, part := range parts {
-		if part == "" {
-			continue
-		}
+func (e Event) Id() string {
+	return e.DeviceName
+}
 
-		keyValue := strings.SplitN(part, "=", 2)
-		if len(keyValue) != 2 {
-			continue
-		}
-		key, value := keyValue[0], keyValue[1]
-		switch key {
-		case "ACTION":
-			e.Action = value
-		case "DEVPATH":
-			e.DevicePath = value
-		case "SUBSYSTEM":
-			e.SubSystem = value
-		case "DEVNAME":
-			e.DeviceName = value
-		default:
-			e.Properties[key] = value
+func (e Event) Sequence() uint64 {
+	return e.Seqnum
+}
+
+// conflicts keeps events of a device, its parents and its children in
+// order, a child can't be added before its parent nor outlive it.
+// Firmware requests are the exception, the probe of the parent waits for
+// them while its event is still being handled.
+func conflicts(a, b job.Job) bool {
+	x, y := a.(Event), b.(Event)
+	if (x.SubSystem == "firmware") != (y.SubSystem == "firmware") {
+		return false
+	}
+	for _, p := range x.paths() {
+		for _, q := range y.paths() {
+			if p == q || strings.HasPrefix(p, q+"/") || strings.HasPrefix(q, p+"/") {
+				return true
+			}
 		}
 	}
-	return e
+	return false
 }
 
-func (e Event) Id() string {
-	return e.DeviceName
+// paths are the device paths the event touches, renames have two.
+func (e Event) paths() []string {
+	if old := e.Properties["DEVPATH_OLD"]; old != "" {
+		return []string{e.DevicePath, old}
+	}
+	return []string{e.DevicePath}
 }
 
 func (e Event) Do() {
 	if modalias, ok := e.Properties["MODALIAS"]; ok {
 		_ = LoadKernelModule(modalias)
 	}
+	if e.SubSystem == "firmware" && e.Action == "add" {
+		loadFirmware(&e)
+	}
+
+	e.identify()
+	applyRules(&e)
+
+	if e.Action == "remove" {
+		_ = udev.RemoveDevice(e.DevicePath)
+	} else if err := udev.WriteDevice(e.Message()); err != nil {
+		log.Printf("failed to record %s: %v", e.DevicePath, err)
+	}
+
+	events.publish(&e)
+}
+
+// Message is the event as republished to subscribers.
+func (e *Event) Message() udev.Event {
+	return udev.Event{
+		Action:     e.Action,
+		DevicePath: e.DevicePath,
+		Subsystem:  e.SubSystem,
+		DeviceName: e.DeviceName,
+		DeviceNode: e.DeviceNode(),
+		Properties: e.Properties,
+		Symlinks:   e.Symlinks,
+		Sequence:   e.Seqnum,
+	}
+}
+
+// DeviceNode is the path of the device node devtmpfs created, if any.
+func (e *Event) DeviceNode() string {
+	if e.DeviceName == "" {
+		return ""
+	}
+	return filepath.Join("/dev", e.DeviceName)
+}
+
+// KernelName is the last component of the device path, e.g. event3.
+func (e *Event) KernelName() string {
+	return filepath.Base(e.DevicePath)
+}
+
+// Attribute reads a sysfs attribute relative to the device.
+func (e *Event) Attribute(name string) (string, bool) {
+	path := filepath.Join(SYSFS_PATH, e.DevicePath, name)
+	if !strings.HasPrefix(path, SYSFS_PATH+"/") {
+		return "", false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false
+	}
+	return strings.TrimRight(string(data), "\n "), true
+}
+
+// Environ returns the event as environment variables for RUN commands.
+func (e *Event) Environ() []string {
+	env := []string{
+		"ACTION=" + e.Action,
+		"DEVPATH=" + e.DevicePath,
+		"SUBSYSTEM=" + e.SubSystem,
+	}
+	if e.DeviceName != "" {
+		env = append(env, "DEVNAME="+e.DeviceNode())
+	}
+	for key, value := range e.Properties {
+		env = append(env, key+"="+value)
+	}
+	return env
+}
+
+// expand substitutes the udev style format specifiers
+//
+//	%k kernel name, %n kernel number, %N device node, %p device path,
+//	%s{attr} sysfs attribute, %E{KEY} property and %% a literal %
+func (e *Event) expand(s string) string {
+	if !strings.Contains(s, "%") {
+		return s
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' || i+1 == len(s) {
+			sb.WriteByte(s[i])
+			continue
+		}
+
+		i++
+		switch s[i] {
+		case 'k':
+			sb.WriteString(e.KernelName())
+		case 'n':
+			name := e.KernelName()
+			sb.WriteString(name[len(strings.TrimRight(name, "0123456789")):])
+		case 'N':
+			sb.WriteString(e.DeviceNode())
+		case 'p':
+			sb.WriteString(e.DevicePath)
+		case '%':
+			sb.WriteByte('%')
+		case 's', 'E':
+			end := strings.IndexByte(s[i:], '}')
+			if i+1 >= len(s) || s[i+1] != '{' || end == -1 {
+				sb.WriteByte('%')
+				sb.WriteByte(s[i])
+				continue
+			}
+			key := s[i+2 : i+end]
+			if s[i] == 's' {
+				value, _ := e.Attribute(key)
+				sb.WriteString(value)
+			} else {
+				sb.WriteString(e.Properties[key])
+			}
+			i += end
+		default:
+			sb.WriteByte('%')
+			sb.WriteByte(s[i])
+		}
+	}
+	return sb.String()
 }
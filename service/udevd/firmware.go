@@ -0,0 +1,114 @@
+/*
+ * Copyright (c) 2025 Manjeet Singh <itsmanjeet1998@gmail.com>.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 3.
+ *
+ * This program is distributed in the hope that it will be useful, but
+ * WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+ * General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"chillos/pkg/decompress"
+)
+
+const (
+	FIRMWARE_PATH = "/lib/firmware"
+
+	// set through the firmware_class.path= kernel parameter
+	FIRMWARE_CUSTOM_PATH = "/sys/module/firmware_class/parameters/path"
+)
+
+// loadFirmware answers a request of the sysfs fallback interface, the
+// kernel waits with the driver probe until loading is written back.
+func loadFirmware(e *Event) {
+	name := e.Properties["FIRMWARE"]
+	if name == "" {
+		return
+	}
+
+	dir := filepath.Join(SYSFS_PATH, e.DevicePath)
+	loading := filepath.Join(dir, "loading")
+
+	data, path, err := readFirmware(name)
+	if err != nil {
+		log.Printf("firmware %s: %v", name, err)
+		_ = os.WriteFile(loading, []byte("-1"), 0)
+		return
+	}
+
+	if err := writeFirmware(dir, data); err != nil {
+		log.Printf("firmware %s: %v", name, err)
+		_ = os.WriteFile(loading, []byte("-1"), 0)
+		return
+	}
+	log.Printf("loaded firmware %s from %s", name, path)
+}
+
+func writeFirmware(dir string, data []byte) error {
+	loading := filepath.Join(dir, "loading")
+	if err := os.WriteFile(loading, []byte("1"), 0); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(filepath.Join(dir, "data"), os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	// the kernel accepts the blob in chunks, Write loops until done
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return os.WriteFile(loading, []byte("0"), 0)
+}
+
+func readFirmware(name string) ([]byte, string, error) {
+	if strings.Contains(name, "..") {
+		return nil, "", fmt.Errorf("invalid firmware name")
+	}
+
+	for _, dir := range firmwareSearchPath() {
+		if path, ok := decompress.Find(filepath.Join(dir, name)); ok {
+			data, err := decompress.ReadFile(path)
+			return data, path, err
+		}
+	}
+	return nil, "", fmt.Errorf("not found")
+}
+
+// firmwareSearchPath follows the order the kernel itself searches in.
+func firmwareSearchPath() []string {
+	release := getKernelVersion()
+
+	var dirs []string
+	if data, err := os.ReadFile(FIRMWARE_CUSTOM_PATH); err == nil {
+		if custom := strings.TrimSpace(string(data)); custom != "" {
+			dirs = append(dirs, custom)
+		}
+	}
+	return append(dirs,
+		filepath.Join(FIRMWARE_PATH, "updates", release),
+		filepath.Join(FIRMWARE_PATH, "updates"),
+		filepath.Join(FIRMWARE_PATH, release),
+		FIRMWARE_PATH,
+	)
+}
@@ -23,33 +23,51 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"chillos/pkg/pool"
+	"chillos/pkg/udev"
 )
 
 const (
 	BUFFER_SIZE = 2048
 	SEARCH_PATH = "/lib/modules"
+
+	// while the queue is full the kernel keeps events in the socket
+	RECEIVE_BUFFER_SIZE = 128 * 1024 * 1024
 )
 
 var (
-	parallel  int
-	trigger   bool
-	eventPool *pool.Pool
+	parallel   int
+	queueLimit int
+	trigger    bool
+	eventQueue *pool.Queue
+
+	// highest SEQNUM handed to the queue
+	received atomic.Uint64
 )
 
 func init() {
 	flag.IntVar(&parallel, "parallel", 10, "Run jobs in parallel")
+	flag.IntVar(&queueLimit, "queue", 256, "Maximum number of queued events")
 	flag.BoolVar(&trigger, "trigger", false, "Trigger all kernel events")
 }
 
 func main() {
 	flag.Parse()
 
-	eventPool = pool.CreatePool(parallel)
-	eventPool.Start()
+	var err error
+	if rules, err = LoadRules(RULES_PATH); err != nil {
+		log.Printf("failed to load rules: %v", err)
+	}
+
+	go listenSubscribers()
+	go loadBootModules()
+
+	eventQueue = pool.NewQueue(parallel, queueLimit, conflicts)
+	eventQueue.Start()
 
 	socket, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_RAW, syscall.NETLINK_KOBJECT_UEVENT)
 	if err != nil {
@@ -65,9 +83,16 @@ func main() {
 		log.Fatalf("failed to bind to socket: %v", err)
 	}
 
+	if err := syscall.SetsockoptInt(socket, syscall.SOL_SOCKET, syscall.SO_RCVBUFFORCE, RECEIVE_BUFFER_SIZE); err != nil {
+		log.Printf("failed to grow receive buffer: %v", err)
+	}
+
 	buffer := make([]byte, BUFFER_SIZE)
 
 	if trigger {
+		// everything is about to be replayed, forget the last boot
+		_ = os.RemoveAll(udev.DatabasePath)
+
 		go func() {
 			time.Sleep(time.Millisecond * 100)
 			filepath.Walk("/sys/devices", func(path string, info fs.FileInfo, err error) error {
@@ -84,7 +109,12 @@ func main() {
 		if err != nil {
 			continue
 		}
-		go eventPool.Submit(parseEvent(buffer, len))
+		// blocks while the queue is full
+		event := parseEvent(buffer, len)
+		eventQueue.Submit(event)
+		if event.Seqnum > received.Load() {
+			received.Store(event.Seqnum)
+		}
 	}
 
 }
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"path/filepath"
@@ -11,7 +12,7 @@ import (
 )
 
 var (
-	cache             []module.Info
+	index             *module.Index
 	kernelModulesPath string
 )
 
@@ -19,20 +20,60 @@ func init() {
 	var err error
 
 	kernelModulesPath = filepath.Join(SEARCH_PATH, getKernelVersion())
-	cache, err = module.LoadCache(kernelModulesPath)
+	index, err = module.LoadIndex(kernelModulesPath)
 	if err != nil {
-		log.Println("failed to read kernel modules cache", kernelModulesPath, err)
+		log.Println("failed to read kernel modules index", kernelModulesPath, err)
+		return
+	}
+
+	config, err := module.LoadConfig(module.ConfigPath)
+	if err != nil {
+		log.Printf("failed to read modules config: %v", err)
+	}
+	if err := config.ReadCmdline(module.CmdlinePath); err != nil {
+		log.Printf("failed to read kernel cmdline: %v", err)
+	}
+	index.SetConfig(config)
+}
+
+// loadBootModules loads the modules configured to be loaded at boot
+// whatever the devices present.
+func loadBootModules() {
+	if index == nil {
+		return
+	}
+
+	loaded := map[string]bool{}
+	for _, name := range index.Config().Load {
+		if err := index.Load(name, "", loaded); err != nil {
+			log.Printf("failed to load %s: %v", name, err)
+		}
 	}
 }
 
+// LoadKernelModule loads every module matching the modalias that is not
+// blacklisted, devices handled by built-in drivers need nothing.
 func LoadKernelModule(alias string) error {
+	if index == nil {
+		return fmt.Errorf("no modules index")
+	}
+
+	modules := index.Resolve(alias)
+	if len(modules) == 0 {
+		return fmt.Errorf("no module found")
+	}
+
 	loaded := map[string]bool{}
-	for _, i := range cache {
-		if match(i.Aliases, alias) {
-			return module.Load(i.Path, kernelModulesPath, loaded)
+	var errs []error
+	for _, name := range modules {
+		if index.Config().IsBlacklisted(name) {
+			continue
+		}
+		if err := index.Load(name, "", loaded); err != nil {
+			errs = append(errs, err)
 		}
 	}
-	return fmt.Errorf("no module found")
+	return errors.Join(errs...)
 }
 
 func getKernelVersion() string {
@@ -48,12 +89,3 @@ func getKernelVersion() string {
 	}
 	return sb.String()
 }
-
-func match(aliases []string, alias string) bool {
-	for _, a := range aliases {
-		if ok, _ := filepath.Match(a, alias); ok {
-			return true
-		}
-	}
-	return false
-}
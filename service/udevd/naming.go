@@ -0,0 +1,263 @@
+/*
+ * Copyright (c) 2025 Manjeet Singh <itsmanjeet1998@gmail.com>.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 3.
+ *
+ * This program is distributed in the hope that it will be useful, but
+ * WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+ * General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"chillos/pkg/kernel/block"
+	"chillos/pkg/kernel/input"
+)
+
+var (
+	pciAddress   = regexp.MustCompile(`^[0-9a-f]{4}:[0-9a-f]{2}:[0-9a-f]{2}\.[0-9a-f]$`)
+	scsiAddress  = regexp.MustCompile(`^\d+:\d+:\d+:\d+$`)
+	usbInterface = regexp.MustCompile(`^\d+-([\d.]+):(\d+\.\d+)$`)
+	serioPort    = regexp.MustCompile(`^serio(\d+)$`)
+)
+
+// identify probes block and input devices and records what was found as
+// ID_* properties, so rules can match on them and persistent symlinks can
+// be derived.
+func (e *Event) identify() {
+	if e.Action == "remove" || e.DeviceName == "" {
+		return
+	}
+
+	if path := pathID(e.DevicePath); path != "" {
+		e.Properties["ID_PATH"] = path
+	}
+
+	switch e.SubSystem {
+	case "block":
+		e.identifyBlock()
+	case "input":
+		if strings.HasPrefix(e.DeviceName, "input/event") {
+			e.identifyInput()
+		}
+	}
+}
+
+func (e *Event) identifyBlock() {
+	if info, err := block.Probe(e.DeviceNode()); err == nil {
+		e.Properties["ID_FS_TYPE"] = info.Type
+		setProperty(e.Properties, "ID_FS_UUID", info.UUID)
+		setProperty(e.Properties, "ID_FS_LABEL", info.Label)
+	}
+
+	if e.Properties["DEVTYPE"] != "partition" {
+		if table, err := block.ReadPartitionTable(e.DeviceNode()); err == nil {
+			e.Properties["ID_PART_TABLE_TYPE"] = table.Type
+			e.Properties["ID_PART_TABLE_UUID"] = table.UUID
+		}
+		return
+	}
+
+	number, err := strconv.Atoi(e.Properties["PARTN"])
+	if err != nil {
+		return
+	}
+	disk := "/dev/" + baseName(parentPath(e.DevicePath))
+	table, err := block.ReadPartitionTable(disk)
+	if err != nil {
+		return
+	}
+	e.Properties["ID_PART_TABLE_TYPE"] = table.Type
+	if p, ok := table.Partition(number); ok {
+		e.Properties["ID_PART_ENTRY_NUMBER"] = strconv.Itoa(p.Number)
+		e.Properties["ID_PART_ENTRY_TYPE"] = p.Type
+		setProperty(e.Properties, "ID_PART_ENTRY_UUID", p.UUID)
+		setProperty(e.Properties, "ID_PART_ENTRY_NAME", p.Label)
+	}
+}
+
+func (e *Event) identifyInput() {
+	device, err := input.OpenDevice(e.DeviceNode())
+	if err != nil {
+		return
+	}
+	defer device.Close()
+
+	if id, err := device.ID(); err == nil {
+		e.Properties["ID_BUS"] = id.Bus()
+		e.Properties["ID_VENDOR_ID"] = fmt.Sprintf("%04x", id.Vendor)
+		e.Properties["ID_MODEL_ID"] = fmt.Sprintf("%04x", id.Product)
+	}
+	if name, err := device.Name(); err == nil {
+		setProperty(e.Properties, "ID_MODEL", sanitize(name))
+	}
+	if phys, err := device.Phys(); err == nil {
+		setProperty(e.Properties, "ID_INPUT_PHYS", phys)
+	}
+	if uniq, err := device.Uniq(); err == nil {
+		setProperty(e.Properties, "ID_SERIAL", sanitize(uniq))
+	}
+
+	e.Properties["ID_INPUT_CLASS"] = e.inputClass()
+}
+
+// inputClass guesses what kind of device it is from its capabilities the
+// same way udev names its links: kbd, mouse, joystick or plain event.
+func (e *Event) inputClass() string {
+	rel, _ := e.Attribute("device/capabilities/rel")
+	abs, _ := e.Attribute("device/capabilities/abs")
+	key, _ := e.Attribute("device/capabilities/key")
+
+	const (
+		relX, relY   = 0, 1
+		absX, absY   = 0, 1
+		keyEsc, keyD = 1, 32
+		btnMouse     = 0x110
+		btnJoystick  = 0x120
+		btnGamepad   = 0x130
+	)
+
+	switch {
+	case hasBit(rel, relX) && hasBit(rel, relY) && hasBit(key, btnMouse):
+		return "mouse"
+	case hasBit(abs, absX) && hasBit(abs, absY) && (hasBit(key, btnJoystick) || hasBit(key, btnGamepad)):
+		return "joystick"
+	case hasBit(abs, absX) && hasBit(abs, absY) && hasBit(key, btnMouse):
+		return "mouse"
+	}
+
+	for bit := keyEsc; bit <= keyD; bit++ {
+		if !hasBit(key, bit) {
+			return "event"
+		}
+	}
+	return "kbd"
+}
+
+// persistentLinks are the /dev/disk and /dev/input symlinks derived from
+// the properties identify found.
+func (e *Event) persistentLinks() []string {
+	var links []string
+	add := func(dir, name string) {
+		if name != "" {
+			links = append(links, dir+"/"+encodeName(name))
+		}
+	}
+
+	switch e.SubSystem {
+	case "block":
+		add("disk/by-uuid", e.Properties["ID_FS_UUID"])
+		add("disk/by-label", e.Properties["ID_FS_LABEL"])
+		add("disk/by-partuuid", e.Properties["ID_PART_ENTRY_UUID"])
+		add("disk/by-partlabel", e.Properties["ID_PART_ENTRY_NAME"])
+		if path := e.Properties["ID_PATH"]; path != "" {
+			if n := e.Properties["ID_PART_ENTRY_NUMBER"]; n != "" {
+				path += "-part" + n
+			}
+			add("disk/by-path", path)
+		}
+
+	case "input":
+		class := e.Properties["ID_INPUT_CLASS"]
+		if class == "" {
+			return nil
+		}
+		if model := e.Properties["ID_MODEL"]; model != "" {
+			id := e.Properties["ID_BUS"] + "-" + model
+			if serial := e.Properties["ID_SERIAL"]; serial != "" {
+				id += "_" + serial
+			}
+			add("input/by-id", id+"-event-"+class)
+		}
+		if path := e.Properties["ID_PATH"]; path != "" {
+			add("input/by-path", path+"-event-"+class)
+		}
+	}
+	return links
+}
+
+// pathID describes where the device is attached from its sysfs path, e.g.
+// pci-0000:00:1f.2-scsi-0:0:0:0 or platform-i8042-serio-0.
+func pathID(devpath string) string {
+	var parts []string
+	components := strings.Split(devpath, "/")
+	for i, c := range components {
+		switch {
+		case pciAddress.MatchString(c):
+			parts = append(parts, "pci-"+c)
+		case scsiAddress.MatchString(c):
+			parts = append(parts, "scsi-"+c)
+		case usbInterface.MatchString(c):
+			m := usbInterface.FindStringSubmatch(c)
+			parts = append(parts, "usb-0:"+m[1]+":"+m[2])
+		case serioPort.MatchString(c):
+			parts = append(parts, "serio-"+serioPort.FindStringSubmatch(c)[1])
+		case i > 0 && components[i-1] == "platform":
+			parts = append(parts, "platform-"+c)
+		}
+	}
+	return strings.Join(parts, "-")
+}
+
+func hasBit(bitmap string, bit int) bool {
+	words := strings.Fields(bitmap)
+	index := len(words) - 1 - bit/64
+	if index < 0 {
+		return false
+	}
+	word, err := strconv.ParseUint(words[index], 16, 64)
+	if err != nil {
+		return false
+	}
+	return word&(1<<(bit%64)) != 0
+}
+
+func setProperty(properties map[string]string, key, value string) {
+	if value != "" {
+		properties[key] = value
+	}
+}
+
+func sanitize(s string) string {
+	return strings.Join(strings.Fields(s), "_")
+}
+
+// encodeName escapes everything but a conservative set of characters the
+// way udev does, so labels like "my disk" become my\x20disk.
+func encodeName(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9',
+			strings.IndexByte("#+-.:=@_", c) != -1:
+			sb.WriteByte(c)
+		default:
+			fmt.Fprintf(&sb, `\x%02x`, c)
+		}
+	}
+	return sb.String()
+}
+
+func parentPath(devpath string) string {
+	if i := strings.LastIndexByte(devpath, '/'); i > 0 {
+		return devpath[:i]
+	}
+	return devpath
+}
+
+func baseName(devpath string) string {
+	return devpath[strings.LastIndexByte(devpath, '/')+1:]
+}
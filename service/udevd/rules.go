@@ -0,0 +1,127 @@
+/*
+ * Copyright (c) 2025 Manjeet Singh <itsmanjeet1998@gmail.com>.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, version 3.
+ *
+ * This program is distributed in the hope that it will be useful, but
+ * WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+ * General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program. If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	RULES_PATH = "/config/udev"
+)
+
+// Rule matches uevents and describes what to do with the device. Every
+// match field is a glob pattern, alternatives are separated by | and a
+// leading ! negates the whole pattern. Empty fields match anything.
+//
+//	{"subsystem": "input", "devname": "input/event*",
+//	 "group": "input", "mode": "0660"}
+//
+// Attribute keys are paths relative to the device in sysfs, so parent
+// attributes can be matched with e.g. "device/name". Symlinks, environ
+// values, run commands and service names are expanded, see expand.
+type Rule struct {
+	Action     string            `json:"action"`
+	Subsystem  string            `json:"subsystem"`
+	DeviceName string            `json:"devname"`
+	Properties map[string]string `json:"properties"`
+	Attributes map[string]string `json:"attributes"`
+
+	Owner    string            `json:"owner"`
+	Group    string            `json:"group"`
+	Mode     string            `json:"mode"`
+	Symlinks []string          `json:"symlinks"`
+	Environ  map[string]string `json:"environ"`
+	Run      []string          `json:"run"`
+	// Service is started when the device is added and stopped when it
+	// goes away, usually a template instance like display@%k.
+	Service string `json:"service"`
+	// Last stops processing of any later rule.
+	Last bool `json:"last"`
+
+	source string
+}
+
+// LoadRules reads every *.json file in path, each holding a list of rules,
+// in lexical order.
+func LoadRules(path string) ([]Rule, error) {
+	files, err := filepath.Glob(filepath.Join(path, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var rules []Rule
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		var list []Rule
+		if err := json.Unmarshal(data, &list); err != nil {
+			return nil, fmt.Errorf("invalid rules %s: %v", file, err)
+		}
+		for i := range list {
+			list[i].source = fmt.Sprintf("%s:%d", filepath.Base(file), i)
+		}
+		rules = append(rules, list...)
+	}
+	return rules, nil
+}
+
+func (r *Rule) Match(e *Event) bool {
+	if !matchPattern(r.Action, e.Action) ||
+		!matchPattern(r.Subsystem, e.SubSystem) ||
+		!matchPattern(r.DeviceName, e.DeviceName) {
+		return false
+	}
+
+	for key, pattern := range r.Properties {
+		if !matchPattern(pattern, e.Properties[key]) {
+			return false
+		}
+	}
+
+	for attr, pattern := range r.Attributes {
+		value, ok := e.Attribute(attr)
+		if !ok || !matchPattern(pattern, value) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchPattern(pattern, value string) bool {
+	if pattern == "" {
+		return true
+	}
+
+	if negated, ok := strings.CutPrefix(pattern, "!"); ok {
+		return !matchPattern(negated, value)
+	}
+
+	for _, alternative := range strings.Split(pattern, "|") {
+		if ok, _ := filepath.Match(alternative, value); ok {
+			return true
+		}
+	}
+	return false
+}
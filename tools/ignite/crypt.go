@@ -0,0 +1,50 @@
+package main
+
+import (
+	"os"
+
+	"chillos/pkg/crypt"
+)
+
+// encryptImage writes plain into a new encrypted image at p, protected by
+// passphrase.
+func encryptImage(plain, p, passphrase string) error {
+	src, err := os.Open(plain)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(p, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	header, key, err := crypt.NewHeader()
+	if err != nil {
+		return err
+	}
+	defer clear(key)
+
+	if err := header.AddKey(key, passphrase, crypt.DefaultIterations); err != nil {
+		return err
+	}
+
+	if err := header.Write(dst); err != nil {
+		return err
+	}
+
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+	if err := dst.Truncate(int64(header.PayloadOffset)*crypt.SectorSize + info.Size()); err != nil {
+		return err
+	}
+
+	if err := crypt.Encrypt(dst, src, header, key); err != nil {
+		return err
+	}
+	return dst.Sync()
+}
@@ -31,6 +31,11 @@ var (
 	debug   bool
 	clean   bool
 
+	slotSize       int
+	dataSize       int
+	encryptData    bool
+	dataPassphrase string
+
 	kernelVersion string
 
 	deviceCachePath string
@@ -59,6 +64,10 @@ func init() {
 	flag.IntVar(&vnc, "vnc", -1, "VNC port")
 	flag.BoolVar(&debug, "debug", false, "Wait for debugger to connect")
 	flag.StringVar(&kernelVersion, "kernel", KERNEL_VERSION, "Specify kernel version")
+	flag.IntVar(&slotSize, "slot-size", 1024, "size of the secondary system slot (in MBs)")
+	flag.IntVar(&dataSize, "data-size", 512, "size of the data partition (in MBs)")
+	flag.BoolVar(&encryptData, "encrypt-data", false, "encrypt the data partition")
+	flag.StringVar(&dataPassphrase, "data-passphrase", "chillos", "passphrase of the encrypted data partition")
 
 }
 
@@ -155,40 +164,81 @@ func main() {
 		}
 	}
 
+	initramfsImage := filepath.Join(imagesPath, "initramfs.img")
+	ensure.Success(
+		ensure.Target(initramfsImage,
+			ensure.Script(
+				ensure.Cmd("install", "-v", "-D", "-m0755", filepath.Join(systemPath, "cmd", "init"), filepath.Join(initramfsPath, "init")),
+				ensure.Cmd("sh", "-e", "-c", "cd "+initramfsPath+" && find . -print0 | cpio --null -ov --format=newc --quiet 2>/dev/null >"+initramfsImage),
+			),
+			filepath.Join(systemPath, "cmd", "init")),
+		"failed to build initramfs image")
+
 	systemImage := filepath.Join(imagesPath, "system.img")
 	for _, dir := range []string{"config", "data"} {
 		systemImageDependencies = append(systemImageDependencies, listFilesRecursive(filepath.Join(projectPath, dir))...)
 	}
+	systemImageDependencies = append(systemImageDependencies, kernelImage, initramfsImage)
 	ensure.Success(
 		ensure.Target(systemImage,
 			ensure.Script(
 				ensure.Cmd("rsync", "-a", "--delete", projectPath+"/config/", systemPath+"/config/"),
 				ensure.Cmd("rsync", "-a", "--delete", projectPath+"/data/", systemPath+"/data/"),
+				ensure.Cmd("install", "-v", "-D", "-m0644", kernelImage, filepath.Join(systemPath, "boot", "kernel.img")),
+				ensure.Cmd("install", "-v", "-D", "-m0644", initramfsImage, filepath.Join(systemPath, "boot", "initramfs.img")),
 				ensure.Cmd("env", "GOOS="+runtime.GOOS, "GOARCH="+runtime.GOARCH, "go", "run", "chillos/cmd/module", "-root", systemPath, "-kernel", kernelVersion, "cache"),
 				ensure.Cmd("mksquashfs", systemPath, systemImage, "-noappend", "-all-root"),
+				func() error { return formatVerity(systemImage) },
 			),
 			systemImageDependencies...,
 		),
 		"failed to build system image")
 
-	initramfsImage := filepath.Join(imagesPath, "initramfs.img")
+	slotImage := filepath.Join(imagesPath, "slot-b.img")
 	ensure.Success(
-		ensure.Target(initramfsImage,
-			ensure.Script(
-				ensure.Cmd("install", "-v", "-D", "-m0755", filepath.Join(systemPath, "cmd", "init"), filepath.Join(initramfsPath, "init")),
-				ensure.Cmd("sh", "-e", "-c", "cd "+initramfsPath+" && find . -print0 | cpio --null -ov --format=newc --quiet 2>/dev/null >"+initramfsImage),
+		ensure.Target(slotImage, func() error {
+			return createSparseImage(slotImage, slotSize)
+		}),
+		"failed to create system slot image")
+
+	dataImage := filepath.Join(imagesPath, "data.img")
+	if encryptData {
+		plainImage := filepath.Join(imagesPath, "data.plain.img")
+		dataImage = filepath.Join(imagesPath, "data.crypt.img")
+		ensure.Success(
+			ensure.Target(dataImage,
+				ensure.Script(
+					func() error { return createSparseImage(plainImage, dataSize-1) },
+					ensure.Cmd("mkfs.ext4", "-q", "-F", plainImage),
+					func() error { return encryptImage(plainImage, dataImage, dataPassphrase) },
+					func() error { return os.Remove(plainImage) },
+				),
 			),
-			systemImage),
-		"failed to build initramfs image")
+			"failed to create encrypted data image")
+	} else {
+		ensure.Success(
+			ensure.Target(dataImage,
+				ensure.Script(
+					func() error { return createSparseImage(dataImage, dataSize) },
+					ensure.Cmd("mkfs.ext4", "-q", "-F", dataImage),
+				),
+			),
+			"failed to create data image")
+	}
 
 	if runTest {
+		rootHash, err := os.ReadFile(systemImage + ".roothash")
+		ensure.Success(err, "failed to read system image root hash")
+
 		args := []string{
 			"-smp", fmt.Sprint(cpu),
 			"-m", fmt.Sprintf("%dM", memory),
 			"-kernel", kernelImage,
 			"-initrd", initramfsImage,
 			"-drive", "file=" + systemImage + ",format=raw",
-			"-append", "-rootfs /dev/sda console=tty0 console=ttyS0",
+			"-drive", "file=" + slotImage + ",format=raw",
+			"-drive", "file=" + dataImage + ",format=raw",
+			"-append", "-slot-a /dev/sda -slot-b /dev/sdb -data /dev/sdc -roothash-a " + string(rootHash) + " console=tty0 console=ttyS0",
 		}
 		args = append(args, device.Emulation...)
 
@@ -262,7 +312,7 @@ func checkup() error {
 	var missing []string
 	for _, bin := range []string{
 		"go", "rsync", "wget", "mksquashfs", "flex",
-		"bison", "bc", "cpio", "make",
+		"bison", "bc", "cpio", "make", "mkfs.ext4",
 	} {
 		if _, err := exec.LookPath(bin); err != nil {
 			missing = append(missing, bin)
@@ -283,6 +333,16 @@ func checkup() error {
 	return nil
 }
 
+func createSparseImage(p string, size int) error {
+	file, err := os.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return file.Truncate(int64(size) * 1024 * 1024)
+}
+
 func listFilesRecursive(s string) []string {
 	var files []string
 	filepath.Walk(s, func(path string, info fs.FileInfo, err error) error {
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+
+	"chillos/pkg/kernel/dm"
+)
+
+// formatVerity appends the dm-verity hash tree to the system image and
+// stores the root hash next to it.
+func formatVerity(image string) error {
+	file, err := os.OpenFile(image, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	size := (info.Size() + dm.VerityBlockSize - 1) &^ (dm.VerityBlockSize - 1)
+	if err := file.Truncate(size); err != nil {
+		return err
+	}
+
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+
+	rootHash, err := dm.FormatVerity(file, file, uint64(size/dm.VerityBlockSize), size, salt)
+	if err != nil {
+		return err
+	}
+
+	if err := file.Sync(); err != nil {
+		return err
+	}
+	return os.WriteFile(image+".roothash", []byte(hex.EncodeToString(rootHash)), 0644)
+}